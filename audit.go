@@ -0,0 +1,71 @@
+package dsmr4p1
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// AuditCodes lists the slow-moving fields an AuditLog records by default: the
+// tariff, the position of the breaker, the equipment identifier and the text
+// message.
+var AuditCodes = []string{ObisTariffIndicator, ObisSwitchPosition, ObisEquipmentID, ObisTextMessage}
+
+// AuditEntry records a change of a field, at the time of the telegram in which
+// it was first seen.
+type AuditEntry struct {
+	Time time.Time
+	Change
+}
+
+// String formats the entry as a line of the audit log, e.g.
+//
+//	2010-12-09T11:30:20+01:00 0-0:96.14.0 "0001" -> "0002"
+func (e AuditEntry) String() string {
+	return fmt.Sprintf("%s %s %q -> %q", e.Time.Format(time.RFC3339), e.Code, e.From, e.To)
+}
+
+// AuditLog records every change of selected fields, as these transitions are
+// what users investigate after the fact. The values present in the first
+// telegram are recorded as a change from the empty value, so the log also
+// shows the state at startup. Feed it telegrams using Add.
+type AuditLog struct {
+	// Codes lists the OBIS codes to record. Defaults to AuditCodes.
+	Codes []string
+
+	// Writer, if not nil, receives each entry as a line.
+	Writer io.Writer
+
+	detector *ChangeDetector
+}
+
+// Add returns the changes in telegram t, ordered by OBIS code, and writes them
+// to the Writer.
+func (a *AuditLog) Add(t Telegram) ([]AuditEntry, error) {
+	r, ts, err := t.parseTimestamped()
+	if err != nil {
+		return nil, err
+	}
+	if a.detector == nil {
+		codes := a.Codes
+		if codes == nil {
+			codes = AuditCodes
+		}
+		a.detector = &ChangeDetector{Thresholds: make(map[string]float64, len(codes))}
+		for _, code := range codes {
+			a.detector.Thresholds[code] = 0
+		}
+	}
+
+	changes := a.detector.add(r)
+	entries := make([]AuditEntry, len(changes))
+	for i, c := range changes {
+		entries[i] = AuditEntry{Time: ts, Change: c}
+		if a.Writer != nil {
+			if _, err := fmt.Fprintln(a.Writer, entries[i]); err != nil {
+				return entries, err
+			}
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,150 @@
+package dsmr4p1
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Baseline describes the telegrams of a particular meter as captured at some
+// point, for example before a firmware update or a meter swap. Compare checks
+// later telegrams against it.
+type Baseline struct {
+	// Identifier is the identifier of the meter in the capture.
+	Identifier string
+
+	// Codes holds, per OBIS code in the capture, the range of its values.
+	Codes map[string]ValueRange
+
+	// Margin widens the range of plausible values for non-cumulative values
+	// (e.g. power and voltage), relative to the largest absolute value in the
+	// capture. For example 0.5 allows for values 50% beyond the captured range.
+	Margin float64
+}
+
+// ValueRange holds the range of the values of an OBIS code in a capture. Min
+// and Max are only meaningful if Numeric is true.
+type ValueRange struct {
+	Numeric  bool
+	Unit     string
+	Min, Max float64
+}
+
+// Deviation describes how a telegram deviates from a Baseline.
+type Deviation struct {
+	Code        string // Empty for deviations not related to a single code.
+	Description string
+}
+
+func (d Deviation) String() string {
+	if d.Code == "" {
+		return d.Description
+	}
+	return d.Code + ": " + d.Description
+}
+
+// NewBaseline builds a Baseline from a capture of telegrams of a single meter.
+func NewBaseline(capture []Telegram) (*Baseline, error) {
+	b := &Baseline{Codes: make(map[string]ValueRange)}
+	for _, t := range capture {
+		r, err := t.Parse()
+		if err != nil {
+			return nil, err
+		}
+		id, err := t.Identifier()
+		if err != nil {
+			return nil, err
+		}
+		b.Identifier = id.Ident
+		for code, values := range r {
+			v, unit, numeric := numericValue(values)
+			vr, seen := b.Codes[code]
+			switch {
+			case !seen:
+				vr = ValueRange{Numeric: numeric, Unit: unit, Min: v, Max: v}
+			case vr.Numeric && numeric:
+				vr.Min = math.Min(vr.Min, v)
+				vr.Max = math.Max(vr.Max, v)
+			}
+			b.Codes[code] = vr
+		}
+	}
+	return b, nil
+}
+
+// Compare compares telegram t against the baseline and returns the deviations:
+// a different identifier, missing or new OBIS codes, different units, decreasing
+// cumulative registers and implausible other values.
+func (b *Baseline) Compare(t Telegram) ([]Deviation, error) {
+	r, err := t.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := t.Identifier()
+	if err != nil {
+		return nil, err
+	}
+
+	var deviations []Deviation
+	if id.Ident != b.Identifier {
+		deviations = append(deviations, Deviation{Description: fmt.Sprintf("identifier changed from %q to %q", b.Identifier, id.Ident)})
+	}
+	var missing, codes []string
+	for code := range b.Codes {
+		if _, ok := r[code]; !ok {
+			missing = append(missing, code)
+		}
+	}
+	sort.Strings(missing)
+	for _, code := range missing {
+		deviations = append(deviations, Deviation{code, "missing"})
+	}
+
+	for code := range r {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		vr, ok := b.Codes[code]
+		if !ok {
+			deviations = append(deviations, Deviation{code, "not in baseline"})
+			continue
+		}
+		v, unit, numeric := numericValue(r[code])
+		if !vr.Numeric || !numeric {
+			continue
+		}
+		if unit != vr.Unit {
+			deviations = append(deviations, Deviation{code, fmt.Sprintf("unit changed from %q to %q", vr.Unit, unit)})
+			continue
+		}
+		if cumulativeUnit(unit) {
+			if v < vr.Min {
+				deviations = append(deviations, Deviation{code, fmt.Sprintf("register decreased to %g (was at least %g)", v, vr.Min)})
+			}
+			continue
+		}
+		margin := b.Margin * math.Max(math.Abs(vr.Min), math.Abs(vr.Max))
+		if v < vr.Min-margin || v > vr.Max+margin {
+			deviations = append(deviations, Deviation{code, fmt.Sprintf("value %g outside plausible range [%g, %g]", v, vr.Min-margin, vr.Max+margin)})
+		}
+	}
+	return deviations, nil
+}
+
+// numericValue returns the value of the last bracket of values, if that
+// contains a value with a unit.
+func numericValue(values []string) (v float64, unit string, ok bool) {
+	if len(values) == 0 {
+		return 0, "", false
+	}
+	v, unit, err := ParseValueWithUnit(values[len(values)-1])
+	return v, unit, err == nil
+}
+
+// cumulativeUnit reports whether values in unit are (typically) cumulative
+// registers, which only ever increase.
+func cumulativeUnit(unit string) bool {
+	return unit == "Wh" || unit == "m3" || unit == "GJ"
+}
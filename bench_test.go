@@ -0,0 +1,213 @@
+package dsmr4p1
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+// sampleTelegram is the example telegram of the DSMR 4.2 specification.
+const sampleTelegram = "" +
+	"/ISk5\\2MT382-1000\r\n" +
+	"\r\n" +
+	"1-3:0.2.8(42)\r\n" +
+	"0-0:1.0.0(101209113020W)\r\n" +
+	"0-0:96.1.1(4B384547303034303436333935353037)\r\n" +
+	"1-0:1.8.1(123456.789*kWh)\r\n" +
+	"1-0:1.8.2(123456.789*kWh)\r\n" +
+	"1-0:2.8.1(123456.789*kWh)\r\n" +
+	"1-0:2.8.2(123456.789*kWh)\r\n" +
+	"0-0:96.14.0(0002)\r\n" +
+	"1-0:1.7.0(01.193*kW)\r\n" +
+	"1-0:2.7.0(00.000*kW)\r\n" +
+	"0-0:17.0.0(016.1*kW)\r\n" +
+	"0-0:96.3.10(1)\r\n" +
+	"0-0:96.7.21(00004)\r\n" +
+	"0-0:96.7.9(00002)\r\n" +
+	"1-0:99.97.0(2)(0-0:96.7.19)(101208152415W)(0000000240*s)(101208151004W)(0000000301*s)\r\n" +
+	"1-0:32.32.0(00002)\r\n" +
+	"1-0:52.32.0(00001)\r\n" +
+	"1-0:72.32.0(00000)\r\n" +
+	"1-0:32.36.0(00000)\r\n" +
+	"1-0:52.36.0(00003)\r\n" +
+	"1-0:72.36.0(00000)\r\n" +
+	"0-0:96.13.1(3031203631203831)\r\n" +
+	"0-0:96.13.0(303132333435363738393A3B3C3D3E3F303132333435363738393A3B3C3D3E3F303132333435363738393A3B3C3D3E3F303132333435363738393A3B3C3D3E3F303132333435363738393A3B3C3D3E3F)\r\n" +
+	"1-0:31.7.0(001*A)\r\n" +
+	"1-0:51.7.0(002*A)\r\n" +
+	"1-0:71.7.0(003*A)\r\n" +
+	"1-0:21.7.0(01.111*kW)\r\n" +
+	"1-0:41.7.0(02.222*kW)\r\n" +
+	"1-0:61.7.0(03.333*kW)\r\n" +
+	"1-0:22.7.0(04.444*kW)\r\n" +
+	"1-0:42.7.0(05.555*kW)\r\n" +
+	"1-0:62.7.0(06.666*kW)\r\n" +
+	"0-1:24.1.0(003)\r\n" +
+	"0-1:96.1.0(3232323241424344313233343536373839)\r\n" +
+	"0-1:24.2.1(101209112500W)(12785.123*m3)\r\n" +
+	"!33F7\r\n"
+
+// loopReader endlessly repeats data.
+type loopReader struct {
+	data []byte
+	off  int
+}
+
+func (r *loopReader) Read(p []byte) (int, error) {
+	n := copy(p, r.data[r.off:])
+	r.off = (r.off + n) % len(r.data)
+	return n, nil
+}
+
+// sampleFrame returns the sample telegram with its CRC trailer, and the
+// telegram as delivered by a Poller.
+func sampleFrame(tb testing.TB) ([]byte, Telegram) {
+	frame := []byte(sampleTelegram)
+	t := Telegram(frame[:bytes.LastIndexByte(frame, '!')+1])
+	if err := Telegram(frame).Validate(); err != nil {
+		tb.Fatal(err)
+	}
+	return frame, t
+}
+
+// pollLoop starts p polling the frame over and over, until the returned
+// function is called.
+func pollLoop(p *Poller, frame []byte) (chan Envelope, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := p.PollEnvelopes(ctx, &loopReader{data: frame})
+	return ch, func() {
+		cancel()
+		for e := range ch {
+			e.Release()
+		}
+	}
+}
+
+func BenchmarkPoll(b *testing.B) {
+	frame, _ := sampleFrame(b)
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := new(Poller).Poll(ctx, &loopReader{data: frame})
+	b.SetBytes(int64(len(frame)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		<-ch
+	}
+	b.StopTimer()
+	cancel()
+	for range ch {
+	}
+}
+
+func BenchmarkPollEnvelopes(b *testing.B) {
+	frame, _ := sampleFrame(b)
+	ch, stop := pollLoop(&Poller{ReuseBuffers: true}, frame)
+	defer stop()
+	b.SetBytes(int64(len(frame)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		e := <-ch
+		e.Release()
+	}
+	b.StopTimer()
+}
+
+func BenchmarkParse(b *testing.B) {
+	_, t := sampleFrame(b)
+	b.SetBytes(int64(len(t)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := t.Parse(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	_, t := sampleFrame(b)
+	b.SetBytes(int64(len(t)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := t.Decode(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// The allocation budgets below are those documented in the README.
+
+// skipIfRace skips allocation tests when the race detector is enabled.
+func skipIfRace(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocation counts are not meaningful with the race detector")
+	}
+}
+
+func TestAllocsPoll(t *testing.T) {
+	skipIfRace(t)
+	frame, _ := sampleFrame(t)
+	ch, stop := pollLoop(new(Poller), frame)
+	defer stop()
+	<-ch
+	if n := testing.AllocsPerRun(100, func() { <-ch }); n > 2 {
+		t.Errorf("framing a telegram: %v allocations, want at most 2", n)
+	}
+}
+
+func TestAllocsPollReuseBuffers(t *testing.T) {
+	skipIfRace(t)
+	frame, _ := sampleFrame(t)
+	ch, stop := pollLoop(&Poller{ReuseBuffers: true}, frame)
+	defer stop()
+	// Fill the pool first.
+	for i := 0; i < 10; i++ {
+		e := <-ch
+		e.Release()
+	}
+	n := testing.AllocsPerRun(100, func() {
+		e := <-ch
+		e.Release()
+	})
+	if n > 0 {
+		t.Errorf("framing a telegram with ReuseBuffers: %v allocations, want 0", n)
+	}
+}
+
+func TestAllocsParse(t *testing.T) {
+	skipIfRace(t)
+	_, tg := sampleFrame(t)
+	n := testing.AllocsPerRun(100, func() {
+		if _, err := tg.Parse(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if n > 6 {
+		t.Errorf("Parse: %v allocations, want at most 6", n)
+	}
+}
+
+func TestAllocsDecode(t *testing.T) {
+	skipIfRace(t)
+	_, tg := sampleFrame(t)
+	n := testing.AllocsPerRun(100, func() {
+		if _, err := tg.Decode(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if n > 44 {
+		t.Errorf("Decode: %v allocations, want at most 44", n)
+	}
+}
+
+func TestAllocsParseTimestamp(t *testing.T) {
+	skipIfRace(t)
+	n := testing.AllocsPerRun(100, func() {
+		if _, err := ParseTimestamp("101209113020W"); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if n > 0 {
+		t.Errorf("ParseTimestamp: %v allocations, want 0", n)
+	}
+}
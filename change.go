@@ -0,0 +1,81 @@
+package dsmr4p1
+
+import (
+	"math"
+	"sort"
+)
+
+// Change is a change in the value of an OBIS code, as detected by a
+// ChangeDetector. The values are the contents of the last bracket, e.g.
+// "01.193*kW".
+type Change struct {
+	Code string
+	From string // Empty for the first value.
+	To   string
+}
+
+// ChangeDetector reports changes in selected fields of a stream of telegrams,
+// ignoring small changes of numeric values. Publishing only the changes
+// drastically reduces the traffic for slowly changing values. Typical use:
+//
+//	d := dsmr4p1.ChangeDetector{Thresholds: map[string]float64{
+//		dsmr4p1.ObisPowerDelivered:  100, // Power delivered changed by more than 100 W.
+//		dsmr4p1.ObisTariffIndicator: 0,   // Any change of tariff.
+//	}}
+type ChangeDetector struct {
+	// Thresholds maps the OBIS codes to watch to the minimum change that is
+	// reported. For values with a unit the threshold is in the unit as returned
+	// by ParseValueWithUnit, e.g. W or Wh. Other values are compared as
+	// strings and reported on any change.
+	Thresholds map[string]float64
+
+	last map[string]string
+}
+
+// Add returns the changes in telegram t compared to the last reported values,
+// ordered by OBIS code. All watched codes present in the first telegram are
+// reported as a change. Codes missing from t are ignored.
+func (d *ChangeDetector) Add(t Telegram) ([]Change, error) {
+	r, err := t.Parse()
+	if err != nil {
+		return nil, err
+	}
+	return d.add(r), nil
+}
+
+// add returns the changes in the parsed telegram r.
+func (d *ChangeDetector) add(r map[string][]string) []Change {
+	if d.last == nil {
+		d.last = make(map[string]string, len(d.Thresholds))
+	}
+
+	var changes []Change
+	for code, threshold := range d.Thresholds {
+		values := r[code]
+		if len(values) == 0 {
+			continue
+		}
+		to := values[len(values)-1]
+		from, seen := d.last[code]
+		if seen && !changed(from, to, threshold) {
+			continue
+		}
+		d.last[code] = to
+		changes = append(changes, Change{Code: code, From: from, To: to})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Code < changes[j].Code })
+	return changes
+}
+
+// changed reports whether value to differs sufficiently from value from.
+func changed(from, to string, threshold float64) bool {
+	v, unit, err := ParseValueWithUnit(to)
+	if err != nil {
+		return from != to
+	}
+	w, fromUnit, err := ParseValueWithUnit(from)
+	if err != nil || unit != fromUnit {
+		return true
+	}
+	return math.Abs(v-w) > threshold
+}
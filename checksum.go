@@ -0,0 +1,113 @@
+package dsmr4p1
+
+import (
+	"fmt"
+
+	"github.com/howeyc/crc16"
+)
+
+// Checksum verifies the checksum in the trailer of a telegram.
+type Checksum interface {
+	// Verify reports whether crc, the trailer following the '!' without its
+	// line ending, is a valid checksum for data, the telegram from the '/' up
+	// to and including the '!'.
+	Verify(data, crc []byte) bool
+}
+
+// ChecksumFunc is an adapter to allow the use of an ordinary function as a
+// Checksum.
+type ChecksumFunc func(data, crc []byte) bool
+
+// Verify calls f(data, crc).
+func (f ChecksumFunc) Verify(data, crc []byte) bool {
+	return f(data, crc)
+}
+
+var (
+	// CRC16 is the checksum specified by DSMR 4 and later: a CRC-16 (see
+	// ibmTableNoXOR) written as four uppercase hexadecimal digits. A Poller
+	// using it also accepts lowercase digits if Lenient is set.
+	CRC16 Checksum = crc16Checksum{}
+
+	// NoChecksum accepts any trailer, including an empty one. Use it for
+	// devices that strip or mangle the checksum, or for DSMR versions before 4
+	// that don't have one.
+	NoChecksum Checksum = ChecksumFunc(func(data, crc []byte) bool {
+		return true
+	})
+)
+
+// crc16Checksum is the type of CRC16, which allows a Poller to recognize it.
+type crc16Checksum struct{}
+
+func (crc16Checksum) Verify(data, crc []byte) bool {
+	return verifyCRC(data, crc, false)
+}
+
+// computeCRC returns the CRC of data as four (uppercase) hexadecimal digits.
+func computeCRC(data []byte) string {
+	return fmt.Sprintf("%04X", crc16.Checksum(data, ibmTableNoXOR))
+}
+
+// verifyCRC reports whether crc holds the CRC of data as four hexadecimal
+// digits. Lowercase digits are only accepted if lenient is set.
+func verifyCRC(data, crc []byte, lenient bool) bool {
+	want, ok := parseCRC(crc, lenient)
+	return ok && want == crc16.Checksum(data, ibmTableNoXOR)
+}
+
+// parseCRC parses a CRC written as four hexadecimal digits. Lowercase digits
+// are only accepted if lenient is set.
+func parseCRC(crc []byte, lenient bool) (uint16, bool) {
+	if len(crc) != 4 {
+		return 0, false
+	}
+	var v uint16
+	for _, c := range crc {
+		switch {
+		case '0' <= c && c <= '9':
+			v = v<<4 | uint16(c-'0')
+		case 'A' <= c && c <= 'F':
+			v = v<<4 | uint16(c-'A'+10)
+		case lenient && 'a' <= c && c <= 'f':
+			v = v<<4 | uint16(c-'a'+10)
+		default:
+			return 0, false
+		}
+	}
+	return v, true
+}
+
+// CRCWriter computes the CRC-16 of a telegram incrementally, as it is written
+// to it, e.g. while the telegram is being read. This saves scanning the
+// telegram once more afterwards. The zero value is ready to use.
+type CRCWriter struct {
+	crc uint16
+}
+
+// Write adds p to the checksum. It never returns an error.
+func (w *CRCWriter) Write(p []byte) (int, error) {
+	w.crc = crc16.Update(w.crc, ibmTableNoXOR, p)
+	return len(p), nil
+}
+
+// Sum16 returns the CRC of the data written so far.
+func (w *CRCWriter) Sum16() uint16 {
+	return w.crc
+}
+
+// Reset resets the checksum, to start with the next telegram.
+func (w *CRCWriter) Reset() {
+	w.crc = 0
+}
+
+// Verify reports whether crc, a trailer without its line ending, holds the CRC
+// of the data written so far as four uppercase hexadecimal digits.
+func (w *CRCWriter) Verify(crc []byte) bool {
+	return w.verify(crc, false)
+}
+
+func (w *CRCWriter) verify(crc []byte, lenient bool) bool {
+	want, ok := parseCRC(crc, lenient)
+	return ok && want == w.crc
+}
@@ -0,0 +1,89 @@
+package dsmr4p1
+
+import (
+	"bytes"
+	"errors"
+)
+
+// ErrorNoConversion indicates that there is no conversion between two profiles.
+var ErrorNoConversion = errors.New("no conversion between these profiles")
+
+// profileConversion describes how to rewrite the telegrams of one profile into
+// those of another.
+type profileConversion struct {
+	// rename maps OBIS codes to the codes they are renamed to.
+	rename map[string]string
+	// drop lists the OBIS codes without an equivalent in the target profile.
+	drop map[string]bool
+	// values maps values of OBIS codes to the values they are replaced by.
+	values map[string]map[string]string
+	// version is the version line of the target profile, which replaces the
+	// version line of the source profile.
+	version string
+}
+
+// The known conversions, by source and target profile.
+var conversions = map[[2]*Profile]profileConversion{
+	// Belgian meters number the tariffs the other way around (tariff 1 is the
+	// day tariff) and add capacity tariff registers. They report the gas volume
+	// not temperature converted, under a separate code that is kept as is, as
+	// it is not the quantity of the DSMR code.
+	{ProfileEMUCS, ProfileDSMR5}: {
+		rename: map[string]string{
+			ObisElectricityDeliveredTariff1: ObisElectricityDeliveredTariff2,
+			ObisElectricityDeliveredTariff2: ObisElectricityDeliveredTariff1,
+			ObisElectricityReceivedTariff1:  ObisElectricityReceivedTariff2,
+			ObisElectricityReceivedTariff2:  ObisElectricityReceivedTariff1,
+		},
+		drop: map[string]bool{
+			ObisAverageDemand: true, ObisMaximumDemand: true,
+			ObisMaximumDemandHistory: true, ObisCurrentLimit: true,
+			ObisSwitchPosition: true,
+		},
+		values: map[string]map[string]string{
+			ObisTariffIndicator: {"0001": "0002", "0002": "0001"},
+		},
+		version: "1-3:0.2.8(50)",
+	},
+}
+
+// Convert rewrites telegram t, which should match another known profile, into
+// a telegram of profile p, for tools that only understand p. For example, a
+// Belgian e-MUCS telegram can be converted into a plain DSMR 5 telegram. If t
+// includes a CRC trailer, the CRC is recomputed. It returns ErrorNoConversion
+// if the profiles can't be converted into each other, and the result of Check
+// if the converted telegram does not match p.
+func (p *Profile) Convert(t Telegram) (Telegram, error) {
+	from, ok := DetectProfile(t)
+	if !ok {
+		return nil, ErrorNoProfile
+	}
+	if from == p {
+		return append(Telegram(nil), t...), nil
+	}
+	c, ok := conversions[[2]*Profile{from, p}]
+	if !ok {
+		return nil, ErrorNoConversion
+	}
+
+	u := t.mapLines(func(code string, line []byte) []byte {
+		switch {
+		case code == from.VersionCode:
+			return []byte(c.version)
+		case code == p.VersionCode || c.drop[code]:
+			return nil
+		}
+		rest := line[len(code):]
+		if v, ok := c.values[code][string(bytes.TrimSuffix(rest[1:], []byte(")")))]; ok {
+			rest = []byte("(" + v + ")")
+		}
+		if renamed, ok := c.rename[code]; ok {
+			code = renamed
+		}
+		return append([]byte(code), rest...)
+	})
+	if err := p.Check(u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
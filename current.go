@@ -0,0 +1,169 @@
+package dsmr4p1
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrorParseCurrent indicates that a current is not a number of amps in
+	// the "value*A" form.
+	ErrorParseCurrent = errors.New("error parsing current: expected amps with unit A")
+	// ErrorInvalidPhase indicates a phase number other than 1, 2 or 3.
+	ErrorInvalidPhase = errors.New("invalid phase: expected 1, 2 or 3")
+	// ErrorPowerSum indicates that the net power in a telegram does not match
+	// the net power of the phases.
+	ErrorPowerSum = errors.New("net power does not match the sum of the phases")
+)
+
+// The OBIS codes per phase (L1, L2, L3).
+var (
+	phaseCurrentCodes        = [3]string{ObisCurrentL1, ObisCurrentL2, ObisCurrentL3}
+	phaseVoltageCodes        = [3]string{ObisVoltageL1, ObisVoltageL2, ObisVoltageL3}
+	phasePowerDeliveredCodes = [3]string{ObisPowerDeliveredL1, ObisPowerDeliveredL2, ObisPowerDeliveredL3}
+	phasePowerReceivedCodes  = [3]string{ObisPowerReceivedL1, ObisPowerReceivedL2, ObisPowerReceivedL3}
+)
+
+// ParseCurrent parses a current such as "002*A", which most meters report in
+// whole amps. Fractional currents such as "001.56*A", reported by some meters,
+// are accepted as well.
+func ParseCurrent(input string) (float64, error) {
+	parts := strings.Split(input, "*")
+	if len(parts) != 2 || parts[1] != "A" {
+		return 0, ErrorParseCurrent
+	}
+	// Whole amps are by far the most common, so try those first.
+	if amps, err := strconv.Atoi(parts[0]); err == nil {
+		return float64(amps), nil
+	}
+	amps, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil || math.IsNaN(amps) || math.IsInf(amps, 0) {
+		return 0, ErrorParseCurrent
+	}
+	return amps, nil
+}
+
+// EstimateCurrent estimates a current (in A) from a power (in W) and a voltage
+// (in V).
+func EstimateCurrent(power, voltage float64) float64 {
+	if voltage == 0 {
+		return 0
+	}
+	return power / voltage
+}
+
+// PhaseCurrent returns the current (in A) of the given phase (1, 2 or 3) in
+// telegram t. As most meters report the current in whole amps, estimate can be
+// set to estimate a fractional current from the power and voltage of the phase
+// instead, when the telegram contains those. The boolean is false if the
+// telegram contains no current for the phase.
+func PhaseCurrent(t Telegram, phase int, estimate bool) (float64, bool, error) {
+	if phase < 1 || phase > 3 {
+		return 0, false, ErrorInvalidPhase
+	}
+	r, err := t.Parse()
+	if err != nil {
+		return 0, false, err
+	}
+
+	if estimate {
+		voltage, vok, err := phaseValue(r, phaseVoltageCodes[phase-1])
+		if err != nil {
+			return 0, false, err
+		}
+		delivered, dok, err := phaseValue(r, phasePowerDeliveredCodes[phase-1])
+		if err != nil {
+			return 0, false, err
+		}
+		received, rok, err := phaseValue(r, phasePowerReceivedCodes[phase-1])
+		if err != nil {
+			return 0, false, err
+		}
+		// Only one of the powers is non-zero, depending on the direction.
+		if vok && (dok || rok) && voltage > 0 {
+			return EstimateCurrent(delivered+received, voltage), true, nil
+		}
+	}
+
+	v := r[phaseCurrentCodes[phase-1]]
+	if len(v) == 0 {
+		return 0, false, nil
+	}
+	amps, err := ParseCurrent(v[0])
+	if err != nil {
+		return 0, false, err
+	}
+	return amps, true, nil
+}
+
+// phaseValue parses the value of code in r, if present.
+func phaseValue(r map[string][]string, code string) (float64, bool, error) {
+	v := r[code]
+	if len(v) == 0 {
+		return 0, false, nil
+	}
+	value, _, err := ParseValueWithUnit(v[0])
+	if err != nil {
+		return 0, false, err
+	}
+	return value, true, nil
+}
+
+// CheckPowerSum checks that the net power in telegram t, i.e. the total power
+// delivered minus the total power received (1-0:1.7.0 and 1-0:2.7.0), matches
+// the net power of the phases, which helps to detect parsing or meter faults.
+// Meters net the power across the phases, so e.g. solar panels on one phase
+// can make the totals differ from the sums per direction. A difference of up
+// to tolerance (in W) is accepted; a tolerance of zero accepts the rounding of
+// the reported values to 1 W. Telegrams without (per phase) powers are
+// skipped. The returned error wraps ErrorPowerSum.
+func CheckPowerSum(t Telegram, tolerance float64) error {
+	r, err := t.Parse()
+	if err != nil {
+		return err
+	}
+	var total, sum float64
+	var totals, phases int
+	for _, dir := range []struct {
+		total  string
+		phases [3]string
+		sign   float64
+	}{
+		{ObisPowerDelivered, phasePowerDeliveredCodes, 1},
+		{ObisPowerReceived, phasePowerReceivedCodes, -1},
+	} {
+		v, ok, err := phaseValue(r, dir.total)
+		if err != nil {
+			return err
+		}
+		if ok {
+			total += dir.sign * v
+			totals++
+		}
+		for _, code := range dir.phases {
+			v, ok, err := phaseValue(r, code)
+			if err != nil {
+				return err
+			}
+			if ok {
+				sum += dir.sign * v
+				phases++
+			}
+		}
+	}
+	if totals == 0 || phases == 0 {
+		return nil
+	}
+	allowed := tolerance
+	if allowed <= 0 {
+		// Each of the values can be off by half a watt.
+		allowed = float64(totals+phases) * 0.5
+	}
+	if math.Abs(total-sum) > allowed+1e-6 {
+		return fmt.Errorf("%w: the net power is %.0f W, the phases net to %.0f W", ErrorPowerSum, total, sum)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package dsmr4p1
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Decimal is an exact fixed-point number: Units × 10^-Scale. The registers of
+// a meter are fixed-point numbers (e.g. F9(3,3)), so a Decimal represents them
+// without the rounding errors of float64, which matters for billing.
+type Decimal struct {
+	Units int64
+	Scale int
+}
+
+// ParseDecimal parses a number without unit, e.g. "001234.567", keeping the
+// number of decimals as its scale.
+func ParseDecimal(s string) (Decimal, error) {
+	scale := 0
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		scale = len(s) - i - 1
+		s = s[:i] + s[i+1:]
+	}
+	units, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return Decimal{}, err
+	}
+	return Decimal{Units: units, Scale: scale}, nil
+}
+
+// Decimal returns the value of the measurement in its base unit as a Decimal,
+// with Precision as its scale, e.g. 1234567 Wh for "001234.567*kWh".
+func (m Measurement) Decimal() Decimal {
+	return Decimal{Units: int64(math.Round(m.Value * math.Pow10(m.Precision))), Scale: m.Precision}
+}
+
+// Float64 returns d as a float64.
+func (d Decimal) Float64() float64 {
+	if d.Scale > 0 {
+		return float64(d.Units) / math.Pow10(d.Scale)
+	}
+	return float64(d.Units) * math.Pow10(-d.Scale)
+}
+
+// rescale returns the units of d at the given (larger) scale.
+func (d Decimal) rescale(scale int) int64 {
+	units := d.Units
+	for s := d.Scale; s < scale; s++ {
+		units *= 10
+	}
+	return units
+}
+
+// Add returns d + e, with the larger of both scales.
+func (d Decimal) Add(e Decimal) Decimal {
+	scale := d.Scale
+	if e.Scale > scale {
+		scale = e.Scale
+	}
+	return Decimal{Units: d.rescale(scale) + e.rescale(scale), Scale: scale}
+}
+
+// Sub returns d - e, with the larger of both scales, e.g. the exact energy
+// between two readings of a register.
+func (d Decimal) Sub(e Decimal) Decimal {
+	return d.Add(Decimal{Units: -e.Units, Scale: e.Scale})
+}
+
+// String formats d with Scale decimals, e.g. "1234.567".
+func (d Decimal) String() string {
+	if d.Scale <= 0 {
+		return strconv.FormatInt(d.rescale(0), 10)
+	}
+	s := strconv.FormatInt(d.Units, 10)
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	if len(s) <= d.Scale {
+		s = strings.Repeat("0", d.Scale-len(s)+1) + s
+	}
+	s = s[:len(s)-d.Scale] + "." + s[len(s)-d.Scale:]
+	if neg {
+		s = "-" + s
+	}
+	return s
+}
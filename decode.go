@@ -0,0 +1,231 @@
+package dsmr4p1
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Decoded holds the values of a telegram in named, typed fields, so consumers
+// don't have to know the OBIS codes. Values with a unit are Measurements in
+// base units: energy in Wh, power in W, voltage in V, current in A and gas in
+// m3. Fields of codes missing from the telegram are left zero.
+type Decoded struct {
+	Identification Identification
+	Version        string    // 1-3:0.2.8
+	Timestamp      time.Time // 0-0:1.0.0
+	EquipmentID    string    // 0-0:96.1.1, decoded.
+
+	ElectricityDeliveredTariff1 Measurement // 1-0:1.8.1
+	ElectricityDeliveredTariff2 Measurement // 1-0:1.8.2
+	ElectricityReceivedTariff1  Measurement // 1-0:2.8.1
+	ElectricityReceivedTariff2  Measurement // 1-0:2.8.2
+	TariffIndicator             int         // 0-0:96.14.0
+
+	PowerDelivered Measurement // 1-0:1.7.0
+	PowerReceived  Measurement // 1-0:2.7.0
+
+	PowerFailures     int // 0-0:96.7.21
+	LongPowerFailures int // 0-0:96.7.9
+	VoltageSagsL1     int // 1-0:32.32.0
+	VoltageSagsL2     int // 1-0:52.32.0
+	VoltageSagsL3     int // 1-0:72.32.0
+	VoltageSwellsL1   int // 1-0:32.36.0
+	VoltageSwellsL2   int // 1-0:52.36.0
+	VoltageSwellsL3   int // 1-0:72.36.0
+
+	PowerFailureLog []PowerFailureEvent // 1-0:99.97.0
+
+	TextMessage string // 0-0:96.13.0, decoded.
+
+	VoltageL1        Measurement // 1-0:32.7.0
+	VoltageL2        Measurement // 1-0:52.7.0
+	VoltageL3        Measurement // 1-0:72.7.0
+	CurrentL1        Measurement // 1-0:31.7.0
+	CurrentL2        Measurement // 1-0:51.7.0
+	CurrentL3        Measurement // 1-0:71.7.0
+	PowerDeliveredL1 Measurement // 1-0:21.7.0
+	PowerDeliveredL2 Measurement // 1-0:41.7.0
+	PowerDeliveredL3 Measurement // 1-0:61.7.0
+	PowerReceivedL1  Measurement // 1-0:22.7.0
+	PowerReceivedL2  Measurement // 1-0:42.7.0
+	PowerReceivedL3  Measurement // 1-0:62.7.0
+
+	GasDelivered   GasReading // 0-n:24.2.1 of the gas meter, with its time of capture.
+	GasEquipmentID string     // 0-n:96.1.0 of the gas meter, decoded.
+	GasChannel     int        // The M-Bus channel of the gas meter, 0 if there is none.
+
+	MBusDevices []MBusDevice // All M-Bus devices, including the gas meter.
+
+	// Extra holds the values of the codes that have no field of their own: the
+	// value returned by the ValueParser registered for the code (see
+	// Register), or else the raw values as a []string.
+	Extra map[string]interface{}
+
+	values map[string][]string
+}
+
+// decoder decodes the values of an OBIS code into a field of Decoded.
+type decoder func(d *Decoded, values []string) error
+
+// decoders holds the decoder per OBIS code. The M-Bus codes (see isMBusCode)
+// are handled by decodeGas and mbusDevices.
+var decoders = map[string]decoder{
+	ObisVersion:                     decodeString(func(d *Decoded) *string { return &d.Version }),
+	ObisTimestamp:                   decodeTime(func(d *Decoded) *time.Time { return &d.Timestamp }),
+	ObisEquipmentID:                 decodeOctets(func(d *Decoded) *string { return &d.EquipmentID }),
+	ObisElectricityDeliveredTariff1: decodeValue(func(d *Decoded) *Measurement { return &d.ElectricityDeliveredTariff1 }),
+	ObisElectricityDeliveredTariff2: decodeValue(func(d *Decoded) *Measurement { return &d.ElectricityDeliveredTariff2 }),
+	ObisElectricityReceivedTariff1:  decodeValue(func(d *Decoded) *Measurement { return &d.ElectricityReceivedTariff1 }),
+	ObisElectricityReceivedTariff2:  decodeValue(func(d *Decoded) *Measurement { return &d.ElectricityReceivedTariff2 }),
+	ObisTariffIndicator:             decodeInt(func(d *Decoded) *int { return &d.TariffIndicator }),
+	ObisPowerDelivered:              decodeValue(func(d *Decoded) *Measurement { return &d.PowerDelivered }),
+	ObisPowerReceived:               decodeValue(func(d *Decoded) *Measurement { return &d.PowerReceived }),
+	ObisPowerFailures:               decodeInt(func(d *Decoded) *int { return &d.PowerFailures }),
+	ObisLongPowerFailures:           decodeInt(func(d *Decoded) *int { return &d.LongPowerFailures }),
+	ObisVoltageSagsL1:               decodeInt(func(d *Decoded) *int { return &d.VoltageSagsL1 }),
+	ObisVoltageSagsL2:               decodeInt(func(d *Decoded) *int { return &d.VoltageSagsL2 }),
+	ObisVoltageSagsL3:               decodeInt(func(d *Decoded) *int { return &d.VoltageSagsL3 }),
+	ObisVoltageSwellsL1:             decodeInt(func(d *Decoded) *int { return &d.VoltageSwellsL1 }),
+	ObisVoltageSwellsL2:             decodeInt(func(d *Decoded) *int { return &d.VoltageSwellsL2 }),
+	ObisVoltageSwellsL3:             decodeInt(func(d *Decoded) *int { return &d.VoltageSwellsL3 }),
+	ObisPowerFailureLog:             decodePowerFailureLog,
+	ObisTextMessage:                 decodeOctets(func(d *Decoded) *string { return &d.TextMessage }),
+	ObisVoltageL1:                   decodeValue(func(d *Decoded) *Measurement { return &d.VoltageL1 }),
+	ObisVoltageL2:                   decodeValue(func(d *Decoded) *Measurement { return &d.VoltageL2 }),
+	ObisVoltageL3:                   decodeValue(func(d *Decoded) *Measurement { return &d.VoltageL3 }),
+	ObisCurrentL1:                   decodeValue(func(d *Decoded) *Measurement { return &d.CurrentL1 }),
+	ObisCurrentL2:                   decodeValue(func(d *Decoded) *Measurement { return &d.CurrentL2 }),
+	ObisCurrentL3:                   decodeValue(func(d *Decoded) *Measurement { return &d.CurrentL3 }),
+	ObisPowerDeliveredL1:            decodeValue(func(d *Decoded) *Measurement { return &d.PowerDeliveredL1 }),
+	ObisPowerDeliveredL2:            decodeValue(func(d *Decoded) *Measurement { return &d.PowerDeliveredL2 }),
+	ObisPowerDeliveredL3:            decodeValue(func(d *Decoded) *Measurement { return &d.PowerDeliveredL3 }),
+	ObisPowerReceivedL1:             decodeValue(func(d *Decoded) *Measurement { return &d.PowerReceivedL1 }),
+	ObisPowerReceivedL2:             decodeValue(func(d *Decoded) *Measurement { return &d.PowerReceivedL2 }),
+	ObisPowerReceivedL3:             decodeValue(func(d *Decoded) *Measurement { return &d.PowerReceivedL3 }),
+}
+
+func decodeString(field func(*Decoded) *string) decoder {
+	return func(d *Decoded, values []string) error {
+		*field(d) = values[0]
+		return nil
+	}
+}
+
+func decodeOctets(field func(*Decoded) *string) decoder {
+	return func(d *Decoded, values []string) (err error) {
+		*field(d), err = ParseOctetString(values[0])
+		return err
+	}
+}
+
+func decodeTime(field func(*Decoded) *time.Time) decoder {
+	return func(d *Decoded, values []string) (err error) {
+		*field(d), err = ParseTimestamp(values[0])
+		return err
+	}
+}
+
+func decodeInt(field func(*Decoded) *int) decoder {
+	return func(d *Decoded, values []string) (err error) {
+		*field(d), err = strconv.Atoi(values[0])
+		return err
+	}
+}
+
+func decodePowerFailureLog(d *Decoded, values []string) (err error) {
+	d.PowerFailureLog, err = ParsePowerFailureLog(values)
+	return err
+}
+
+// decodeValue decodes a value with a unit.
+func decodeValue(field func(*Decoded) *Measurement) decoder {
+	return func(d *Decoded, values []string) (err error) {
+		*field(d), err = ParseMeasurement(values[0])
+		return err
+	}
+}
+
+// Decode parses the telegram into a Decoded. Errors name the OBIS code whose
+// value couldn't be decoded.
+func (t Telegram) Decode() (*Decoded, error) {
+	r, err := t.Parse()
+	if err != nil {
+		return nil, err
+	}
+	id, err := t.Identifier()
+	if err != nil {
+		return nil, err
+	}
+	d := &Decoded{Identification: id, Extra: make(map[string]interface{}), values: r}
+	for code, values := range r {
+		dec, ok := decoders[code]
+		switch {
+		case ok && len(values) > 0:
+			err = dec(d, values)
+		case ok || isMBusCode(code):
+		default:
+			if parse, ok := lookupParser(code); ok {
+				d.Extra[code], err = parse(values)
+			} else {
+				d.Extra[code] = values
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", code, err)
+		}
+	}
+	if err := d.decodeGas(); err != nil {
+		return nil, err
+	}
+	if d.MBusDevices, err = mbusDevices(r); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// gasChannel returns the M-Bus channel of the gas meter, using get to look up
+// the values of the codes, or 0 if there is none. Without device types, a
+// reading on channel 1 is assumed to be gas.
+func gasChannel(get func(code string) ([]string, bool)) int {
+	for n := 1; n <= 4; n++ {
+		v, _ := get(fmt.Sprintf("0-%d:24.1.0", n))
+		if len(v) > 0 {
+			if deviceType, err := strconv.Atoi(v[0]); err == nil && DeviceType(deviceType) == DeviceGas {
+				return n
+			}
+		}
+	}
+	if _, ok := get(ObisGasDelivered); ok {
+		return 1
+	}
+	return 0
+}
+
+// decodeGas decodes the reading of the gas meter, on whichever M-Bus channel
+// it is.
+func (d *Decoded) decodeGas() error {
+	channel := gasChannel(func(code string) ([]string, bool) {
+		v, ok := d.values[code]
+		return v, ok
+	})
+	if channel == 0 {
+		return nil
+	}
+
+	d.GasChannel = channel
+	code := fmt.Sprintf("0-%d:24.2.1", channel)
+	if v, ok := d.values[code]; ok {
+		var err error
+		if d.GasDelivered, err = ParseGasReading(v); err != nil {
+			return fmt.Errorf("%s: %w", code, err)
+		}
+	}
+	if v := d.values[fmt.Sprintf("0-%d:96.1.0", channel)]; len(v) > 0 {
+		var err error
+		if d.GasEquipmentID, err = ParseOctetString(v[0]); err != nil {
+			return fmt.Errorf("0-%d:96.1.0: %w", channel, err)
+		}
+	}
+	return nil
+}
@@ -3,28 +3,38 @@ package dsmr4p1
 
 import (
 	"bufio"
-	"bytes"
+	"context"
 	"errors"
-	"fmt"
 	"io"
-	"log"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/howeyc/crc16"
 )
 
-// Constants for now as I'm assuming all dutch smartmeters will be in the
-// same Dutch timezone.
-const (
-	summerTimezone = "CEST"
-	winterTimezone = "CET"
+// For now I'm assuming all dutch smartmeters will be in the same Dutch
+// timezone. The DST indicator in a timestamp selects between the offsets of
+// CET and CEST.
+var (
+	summerTimezone = time.FixedZone("CEST", 2*60*60)
+	winterTimezone = time.FixedZone("CET", 1*60*60)
+)
+
+// The Dutch location is only loaded once; see dutchLocation.
+var (
+	dutchOnce sync.Once
+	dutchLoc  *time.Location
+	dutchErr  error
 )
 
 var (
 	// ErrorParseTimestamp indicates that there was an error parsing a timestamp.
 	ErrorParseTimestamp = errors.New("error parsing timestamp: missing DST indicator")
+	// ErrorInvalidTimestamp indicates that a timestamp is not of the form
+	// YYMMDDhhmmssX.
+	ErrorInvalidTimestamp = errors.New("error parsing timestamp: invalid format")
 	// ErrorParseValueWithUnit indicates that there was an error parsing a value string
 	// (i.e., a string containing both a value and a unit)
 	ErrorParseValueWithUnit = errors.New("error parsing string that should contain both a value and a unit")
@@ -41,34 +51,82 @@ var (
 // ParseTimestamp parses the timestamp format used in the dutch smartmeters. Do
 // note this function assumes the CET/CEST timezone.
 func ParseTimestamp(timestamp string) (time.Time, error) {
+	return ParseTimestampBytes([]byte(timestamp))
+}
+
+// ParseTimestampBytes is like ParseTimestamp, but parses a byte slice, e.g.
+// straight from a telegram. It does not allocate.
+func ParseTimestampBytes(timestamp []byte) (time.Time, error) {
 	// The format for the timestamp is:
 	// YYMMDDhhmmssX
 	// The value used for X determines whether DST is active.
 	// S (summer?) means yes, W (winter?) means no.
-
-	var timezone string
+	if len(timestamp) == 0 {
+		return time.Time{}, ErrorInvalidTimestamp
+	}
+	var zone *time.Location
 	switch timestamp[len(timestamp)-1] {
 	case 'S':
-		timezone = summerTimezone
+		zone = summerTimezone
 	case 'W':
-		timezone = winterTimezone
+		zone = winterTimezone
 	default:
 		return time.Time{}, ErrorParseTimestamp
 	}
+	if len(timestamp) != 13 {
+		return time.Time{}, ErrorInvalidTimestamp
+	}
+	ts, err := parseClock(timestamp[:12], zone)
+	if err != nil {
+		return time.Time{}, err
+	}
 
-	// To make sure parsing is always consistent and indepentent of the the local
+	// To make sure times are always consistent and indepentent of the the local
 	// timezone of the host this code is running on, let's for now assume Dutch
 	// time.
-	loc, err := time.LoadLocation("Europe/Amsterdam")
-
-	timestamp = timestamp[:len(timestamp)-1] + " " + timezone
-	ts, err := time.ParseInLocation("060102150405 MST", timestamp, loc)
+	loc, err := dutchLocation()
 	if err != nil {
-		return ts, err
+		return time.Time{}, err
+	}
+	return ts.In(loc), nil
+}
+
+// parseClock parses a timestamp of the form YYMMDDhhmmss in the given location.
+func parseClock(timestamp []byte, loc *time.Location) (time.Time, error) {
+	var fields [6]int
+	for i := range fields {
+		hi, lo := timestamp[2*i]-'0', timestamp[2*i+1]-'0'
+		if hi > 9 || lo > 9 {
+			return time.Time{}, ErrorInvalidTimestamp
+		}
+		fields[i] = int(hi)*10 + int(lo)
+	}
+	// Two digit years are interpreted like the "06" layout of the time package.
+	year := 2000 + fields[0]
+	if fields[0] >= 69 {
+		year -= 100
+	}
+	month, day := time.Month(fields[1]), fields[2]
+	if month < time.January || month > time.December || fields[3] > 23 || fields[4] > 59 || fields[5] > 59 {
+		return time.Time{}, ErrorInvalidTimestamp
+	}
+	ts := time.Date(year, month, day, fields[3], fields[4], fields[5], 0, loc)
+	if ts.Day() != day {
+		// time.Date normalizes e.g. February 30th to March 2nd.
+		return time.Time{}, ErrorInvalidTimestamp
 	}
 	return ts, nil
 }
 
+// dutchLocation returns the Europe/Amsterdam location, which is loaded only
+// once.
+func dutchLocation() (*time.Location, error) {
+	dutchOnce.Do(func() {
+		dutchLoc, dutchErr = time.LoadLocation("Europe/Amsterdam")
+	})
+	return dutchLoc, dutchErr
+}
+
 // ParseValueWithUnit parses the provided string into a float and a unit. If the
 // unit starts with "k" the value is multiplied by 1000 and the "k" is removed
 // from the unit.
@@ -90,65 +148,11 @@ func ParseValueWithUnit(input string) (value float64, unit string, err error) {
 	return
 }
 
-// Starts polling and attempts to parse a telegram.
-func startPolling(input io.Reader, ch chan Telegram) {
-	br := bufio.NewReader(input)
-	for {
-		// Read until we find a '/', which should be the beginning of the telegram.
-		_, err := br.ReadBytes('/')
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			log.Println(err)
-			continue
-		}
-
-		// Unread the byte as the '/' is also part of the CRC computation.
-		err = br.UnreadByte()
-		if err != nil {
-			log.Println(err)
-			continue
-		}
-
-		// The '!' character signals the end of the telegram.
-		data, err := br.ReadBytes('!')
-		if err != nil {
-			log.Println(err)
-			continue
-		}
-		// The four hexadecimal characters are the CRC-16 of the preceding data, delimitted by
-		// a carriage return.
-		crcBytes, err := br.ReadBytes('\n')
-		if err != nil {
-			log.Println(err)
-			continue
-		}
-
-		if len(crcBytes) != 6 {
-			log.Println("Unexpected number of CRC bytes.")
-			continue // Maybe we can recover?
-		}
-		dataCRC := string(crcBytes[:4])
-		computedCRC := fmt.Sprintf("%04X", crc16.Checksum(data, ibmTableNoXOR))
-
-		if dataCRC == computedCRC {
-			t := Telegram(data)
-			ch <- t
-		} else {
-			log.Printf("CRC values do not match: %s vs %s\n", dataCRC, computedCRC)
-		}
-	}
-	// Close the channel (should only happen with EOF, allows for clean exit).
-	close(ch)
-}
-
 // Poll starts polling the P1 port represented by input (an io.Reader). It will
 // start a goroutine and received telegrams are put into returned channel. Only
 // telegrams whose CRC value are correct are put into the channel.
 func Poll(input io.Reader) chan Telegram {
-	ch := make(chan Telegram)
-	go startPolling(input, ch)
-	return ch
+	return new(Poller).Poll(context.Background(), input)
 }
 
 // Some code to simulate a smartmeter
@@ -156,39 +160,47 @@ type delayedReader struct {
 	rd     *bufio.Reader
 	delim  byte
 	ticker *time.Ticker
+
+	// period is the interval of the ticker. A nil ticker is started on the
+	// first wall clock boundary, i.e. a multiple of period.
+	period time.Duration
+
+	// burst is the number of telegrams released per tick, and left the number
+	// that may still be released before the next tick.
+	burst int
+	left  int
 }
 
 func (dr *delayedReader) Read(p []byte) (n int, err error) {
 	tmp, _ := dr.rd.Peek(len(p))
-	i1 := bytes.IndexByte(tmp, dr.delim)
-	// No start of telegram here, just continue reading
-	if i1 == -1 {
-		n, err = dr.rd.Read(p)
-		return
-	}
-	// So there is a '/' coming up. If the '/' is not the first charactar, simply
-	// let read until it is.
-	if i1 != 0 {
-		n, err = dr.rd.Read(p[:i1])
-		return
+	for i, c := range tmp {
+		if c != dr.delim {
+			continue
+		}
+		// A new telegram is coming up. If the current burst is used up, let
+		// read until the '/' and then wait until the ticker fires.
+		if dr.left == 0 {
+			if i > 0 {
+				return dr.rd.Read(p[:i])
+			}
+			dr.wait()
+			dr.left = dr.burst
+		}
+		dr.left--
 	}
+	// The telegrams up to here are all part of the current burst.
+	return dr.rd.Read(p)
+}
 
-	// i1 == 0, so tmp[0] == '/': a new telegram is coming up. Let's wait until
-	// the ticker fires.
-	<-dr.ticker.C
-
-	// Ok, but how much should we return? Is there maybe another '/'?
-	i2 := bytes.IndexByte(tmp[i1+1:], dr.delim)
-
-	// If there isn't, just read the rest.
-	if i2 == -1 {
-		n, err = dr.rd.Read(p)
+// wait waits until the next tick.
+func (dr *delayedReader) wait() {
+	if dr.ticker == nil {
+		now := time.Now()
+		time.Sleep(now.Truncate(dr.period).Add(dr.period).Sub(now))
+		dr.ticker = time.NewTicker(dr.period)
 		return
 	}
-
-	// Finally, if there is another '/' coming up, read until that character.
-	n, err = dr.rd.Read(p[:i2])
-	return
+	<-dr.ticker.C
 }
 
 // RateLimit takes a io.Reader (typically the output of a os.Open) and delay the
@@ -197,6 +209,43 @@ func (dr *delayedReader) Read(p []byte) (n int, err error) {
 // smartmeter to a file. Then in your test program open the file and use the
 // resulting io.Reader with this function. The resulting io.Reader will mimick a
 // real smart-meter that outputs a telegram every n seconds (typically 10).
+//
+// A delay of zero (or less) disables throttling: the telegrams are then pushed
+// as fast as the consumer reads them, e.g. to load test a consumer with months
+// of data in seconds.
 func RateLimit(input io.Reader, delay time.Duration) io.Reader {
-	return &delayedReader{rd: bufio.NewReader(input), delim: '/', ticker: time.NewTicker(delay)}
+	if delay <= 0 {
+		return input
+	}
+	return RateLimitBursts(input, delay, 1)
+}
+
+// RateLimitBursts is like RateLimit, but releases the telegrams in bursts of n
+// back-to-back telegrams every n*delay, mimicking bridges that batch the
+// telegrams of the meter. The average rate is the same as that of RateLimit.
+func RateLimitBursts(input io.Reader, delay time.Duration, n int) io.Reader {
+	if delay <= 0 {
+		return input
+	}
+	if n < 1 {
+		n = 1
+	}
+	period := delay * time.Duration(n)
+	return &delayedReader{
+		rd:     bufio.NewReader(input),
+		delim:  '/',
+		ticker: time.NewTicker(period),
+		period: period,
+		burst:  n,
+	}
+}
+
+// RateLimitAligned is like RateLimit, but emits the telegrams on wall clock
+// boundaries, e.g. exactly on :00, :10, :20 seconds for a delay of ten
+// seconds, so replayed data lines up with dashboards and aggregation windows.
+func RateLimitAligned(input io.Reader, delay time.Duration) io.Reader {
+	if delay <= 0 {
+		return input
+	}
+	return &delayedReader{rd: bufio.NewReader(input), delim: '/', period: delay, burst: 1}
 }
@@ -0,0 +1,98 @@
+package dsmr4p1
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+// EnergyEstimator estimates the total electricity delivered to the client at a
+// finer resolution than the registers offer, by integrating the instantaneous
+// power (1-0:1.7.0) between increments of the registers. Whenever a register
+// ticks the estimate is reset to the register value, so the error stays below
+// one register step. As the estimate is anchored to the (lifetime) registers,
+// it simply continues after a restart. The zero value is ready to use.
+type EnergyEstimator struct {
+	register   float64
+	resolution float64
+	extra      float64
+	last       time.Time
+	lastPower  float64
+	seen       bool
+}
+
+// Add processes telegram t.
+func (e *EnergyEstimator) Add(t Telegram) error {
+	r, ts, err := t.parseTimestamped()
+	if err != nil {
+		return err
+	}
+
+	var register, resolution float64
+	codes := []string{ObisElectricityDelivered}
+	if _, ok := r[ObisElectricityDelivered]; !ok {
+		codes = []string{ObisElectricityDeliveredTariff1, ObisElectricityDeliveredTariff2}
+	}
+	for _, code := range codes {
+		if len(r[code]) == 0 {
+			continue
+		}
+		value, _, err := ParseValueWithUnit(r[code][0])
+		if err != nil {
+			return err
+		}
+		register += value
+		resolution = math.Max(resolution, registerResolution(r[code][0]))
+	}
+	var power float64
+	if len(r[ObisPowerDelivered]) > 0 {
+		if power, _, err = ParseValueWithUnit(r[ObisPowerDelivered][0]); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case !e.seen || register != e.register:
+		e.extra = 0
+	case ts.After(e.last):
+		// Trapezoidal integration of the power (W) over the interval.
+		hours := ts.Sub(e.last).Hours()
+		e.extra += (e.lastPower + power) / 2 * hours
+		// The register didn't tick, so less than one step was consumed.
+		if e.extra >= resolution {
+			e.extra = math.Nextafter(resolution, 0)
+		}
+	}
+	e.register, e.resolution = register, resolution
+	e.last, e.lastPower, e.seen = ts, power, true
+	return nil
+}
+
+// Estimate returns the estimated total energy delivered, in Wh.
+func (e *EnergyEstimator) Estimate() float64 {
+	return e.register + e.extra
+}
+
+// Resolution returns the resolution of the registers, in Wh, which bounds the
+// error of the estimate.
+func (e *EnergyEstimator) Resolution() float64 {
+	return e.resolution
+}
+
+// registerResolution returns the smallest step (in base units) of a value
+// such as "001234.567*kWh".
+func registerResolution(input string) float64 {
+	value := input
+	if i := strings.IndexByte(input, '*'); i != -1 {
+		value = input[:i]
+	}
+	decimals := 0
+	if i := strings.IndexByte(value, '.'); i != -1 {
+		decimals = len(value) - i - 1
+	}
+	step := math.Pow10(-decimals)
+	if strings.Contains(input, "*k") {
+		step *= 1000
+	}
+	return step
+}
@@ -0,0 +1,68 @@
+package dsmr4p1
+
+import (
+	"sync"
+	"time"
+)
+
+// bufferPool holds the buffers used by a Poller with ReuseBuffers set.
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 2048)
+		return &b
+	},
+}
+
+// releaseBuffer puts buf, now holding data, back into the pool. It does nothing
+// if buf is nil.
+func releaseBuffer(buf *[]byte, data []byte) {
+	if buf == nil {
+		return
+	}
+	*buf = data[:0]
+	bufferPool.Put(buf)
+}
+
+// Envelope holds a received telegram together with some information about how
+// and when it was received.
+type Envelope struct {
+	// Telegram is the CRC checked telegram, from the '/' up to and including
+	// the '!'.
+	Telegram Telegram
+
+	// Raw is the complete frame exactly as it was read: the telegram followed
+	// by the CRC trailer and its line ending. Useful for archiving or forwarding
+	// byte-exact telegrams.
+	Raw []byte
+
+	// Received is the (host) time at which the frame was read.
+	Received time.Time
+
+	// Burst reports whether the frame arrived back-to-back with the previous
+	// one, i.e. its header was read from the input together with the trailer
+	// of the previous frame, as happens with bridges that batch telegrams.
+	// Received then tells when the batch arrived rather than when the meter
+	// emitted the telegram, so use the telegram's timestamp for anything
+	// interval related. Inputs that return many frames per read, such as files
+	// and in-memory readers, mark all but the first frame of each read.
+	Burst bool
+
+	// Offset is the position of the frame in the input, in bytes.
+	Offset int64
+
+	// Normalized reports whether the numbers in Telegram were normalized (see
+	// Poller.NormalizeNumbers), in which case it differs from the frame in Raw.
+	Normalized bool
+
+	buf *[]byte
+}
+
+// Release returns the buffer of the envelope to the pool it was leased from
+// (see Poller.ReuseBuffers), after which neither the envelope's Telegram nor
+// its Raw frame may be used anymore. Release must be called only once. It does
+// nothing for envelopes whose buffer wasn't leased from a pool.
+func (e Envelope) Release() {
+	if e.buf != nil {
+		releaseBuffer(e.buf, *e.buf)
+	}
+}
@@ -0,0 +1,188 @@
+package dsmr4p1
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Event is something that happened apart from the telegrams themselves, e.g. a
+// change of tariff or a reconnect of the port. Use a type switch on the
+// concrete types to get at their payload.
+type Event interface {
+	fmt.Stringer
+	isEvent()
+}
+
+// MeterSwapEvent reports that the equipment identifier (0-0:96.1.1) changed,
+// i.e. the meter was replaced.
+type MeterSwapEvent struct {
+	Time     time.Time
+	From, To string // Decoded equipment identifiers.
+}
+
+// TariffChangeEvent reports a change of the tariff indicator (0-0:96.14.0).
+type TariffChangeEvent struct {
+	Time     time.Time
+	From, To int
+}
+
+// AlertEvent reports a new message from the grid operator, i.e. a change of
+// the text message (0-0:96.13.0) or the numeric message (0-0:96.13.1) to a
+// non-empty value.
+type AlertEvent struct {
+	Time    time.Time
+	Message string // Decoded text message.
+	Code    string // Decoded numeric message.
+}
+
+// GapEvent reports that telegrams were missed between two received telegrams.
+type GapEvent struct {
+	From, To time.Time // The timestamps of the telegrams around the gap.
+	Missed   int
+}
+
+// ProfileSwitchEvent reports that a ProfileManager switched profiles.
+type ProfileSwitchEvent struct {
+	Time     time.Time
+	From, To *Profile // From is nil when the first profile is selected.
+}
+
+// ReconnectEvent reports that reading from the port of a PortReader failed, so
+// it reconnects.
+type ReconnectEvent struct {
+	Time time.Time
+	Err  error // The reason the port was reopened.
+}
+
+func (MeterSwapEvent) isEvent()     {}
+func (TariffChangeEvent) isEvent()  {}
+func (AlertEvent) isEvent()         {}
+func (GapEvent) isEvent()           {}
+func (ProfileSwitchEvent) isEvent() {}
+func (ReconnectEvent) isEvent()     {}
+
+func (e MeterSwapEvent) String() string {
+	return fmt.Sprintf("%s meter swapped from %q to %q", e.Time.Format(time.RFC3339), e.From, e.To)
+}
+
+func (e TariffChangeEvent) String() string {
+	return fmt.Sprintf("%s tariff changed from %d to %d", e.Time.Format(time.RFC3339), e.From, e.To)
+}
+
+func (e AlertEvent) String() string {
+	return fmt.Sprintf("%s alert %q (code %q)", e.Time.Format(time.RFC3339), e.Message, e.Code)
+}
+
+func (e GapEvent) String() string {
+	return fmt.Sprintf("%s gap of %d telegrams until %s", e.From.Format(time.RFC3339), e.Missed, e.To.Format(time.RFC3339))
+}
+
+func (e ProfileSwitchEvent) String() string {
+	from := "none"
+	if e.From != nil {
+		from = e.From.Name
+	}
+	return fmt.Sprintf("%s profile switched from %s to %s", e.Time.Format(time.RFC3339), from, e.To.Name)
+}
+
+func (e ReconnectEvent) String() string {
+	return fmt.Sprintf("%s reconnecting after %v", e.Time.Format(time.RFC3339), e.Err)
+}
+
+// EventDetector derives events from a stream of telegrams, so applications
+// don't have to infer them from the values themselves. Apart from the
+// selection of the first profile, the first telegram only sets the baseline.
+// Combined with PortReader.OnEvent, all events can be
+// funneled into a single channel:
+//
+//	events := make(chan dsmr4p1.Event, 16)
+//	reader.OnEvent = func(e dsmr4p1.Event) { events <- e }
+//	for t := range dsmr4p1.Poll(reader) {
+//		evs, _ := detector.Add(t)
+//		for _, e := range evs {
+//			events <- e
+//		}
+//	}
+type EventDetector struct {
+	// Interval is the interval at which the meter emits telegrams, used to
+	// detect gaps. Defaults to ten seconds (DSMR 4).
+	Interval time.Duration
+
+	// Profiles, if not nil, checks the telegrams and its profile switches are
+	// reported.
+	Profiles *ProfileManager
+
+	seen        bool
+	last        time.Time
+	equipmentID string
+	tariff      string
+	message     string
+	code        string
+}
+
+// Add returns the events that telegram t reveals.
+func (d *EventDetector) Add(t Telegram) ([]Event, error) {
+	r, ts, err := t.parseTimestamped()
+	if err != nil {
+		return nil, err
+	}
+	var events []Event
+	if d.Profiles != nil {
+		from := d.Profiles.Profile
+		d.Profiles.Add(t)
+		if to := d.Profiles.Profile; to != from {
+			events = append(events, ProfileSwitchEvent{Time: ts, From: from, To: to})
+		}
+	}
+
+	equipmentID, tariff := lastValue(r[ObisEquipmentID]), lastValue(r[ObisTariffIndicator])
+	message, code := lastValue(r[ObisTextMessage]), lastValue(r[ObisTextMessageCode])
+	if d.seen {
+		interval := d.Interval
+		if interval <= 0 {
+			interval = 10 * time.Second
+		}
+		if n := missedBetween(d.last, ts, interval); n > 0 {
+			events = append(events, GapEvent{From: d.last, To: ts, Missed: n})
+		}
+		if equipmentID != "" && d.equipmentID != "" && equipmentID != d.equipmentID {
+			events = append(events, MeterSwapEvent{Time: ts, From: octets(d.equipmentID), To: octets(equipmentID)})
+		}
+		if tariff != "" && d.tariff != "" && tariff != d.tariff {
+			from, _ := strconv.Atoi(d.tariff)
+			to, _ := strconv.Atoi(tariff)
+			events = append(events, TariffChangeEvent{Time: ts, From: from, To: to})
+		}
+		if (message != d.message || code != d.code) && (message != "" || code != "") {
+			events = append(events, AlertEvent{Time: ts, Message: octets(message), Code: octets(code)})
+		}
+	}
+	if ts.After(d.last) {
+		d.last = ts
+	}
+	if equipmentID != "" {
+		d.equipmentID = equipmentID
+	}
+	if tariff != "" {
+		d.tariff = tariff
+	}
+	d.message, d.code, d.seen = message, code, true
+	return events, nil
+}
+
+// lastValue returns the last of values, or an empty string.
+func lastValue(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+// octets decodes octet string s, or returns s as is if it isn't one.
+func octets(s string) string {
+	if decoded, err := ParseOctetString(s); err == nil {
+		return decoded
+	}
+	return s
+}
@@ -0,0 +1,11 @@
+module github.com/mhe/dsmr4p1/examples
+
+go 1.15
+
+require (
+	github.com/mhe/dsmr4p1 v0.0.0
+	github.com/tarm/serial v0.0.0-20180830185346-98f6abe2eb07
+	golang.org/x/sys v0.0.0-20201119102817-f84b799fce68 // indirect
+)
+
+replace github.com/mhe/dsmr4p1 => ../
@@ -27,10 +27,11 @@ func main() {
 	var err error
 	if *testfile == "" {
 		c := &serial.Config{Name: *device, Baud: *baudrate}
-		input, err = serial.OpenPort(c)
-		if err != nil {
-			log.Fatal(err)
-		}
+		opener := dsmr4p1.PortOpenerFunc(func() (dsmr4p1.Port, error) {
+			return serial.OpenPort(c)
+		})
+		// Reopen the serial port after a second if it ever fails.
+		input = dsmr4p1.NewPortReader(opener, time.Second)
 
 	} else {
 		input, err = os.Open(*testfile)
@@ -0,0 +1,14 @@
+// Small wrapper around dsmr4p1.wasm (see main.go). Load wasm_exec.js from the
+// Go distribution first, then:
+//
+//   const dsmr = await loadDSMR("dsmr4p1.wasm");
+//   const result = dsmr.parse(text);
+//   // result.vendor, result.identifier, result.timestamp, result.values["1-0:1.7.0"], result.error
+async function loadDSMR(url) {
+  const go = new Go();
+  const { instance } = await WebAssembly.instantiateStreaming(fetch(url), go.importObject);
+  go.run(instance);
+  return {
+    parse: (text) => globalThis.dsmrParse(text),
+  };
+}
@@ -0,0 +1,71 @@
+//go:build js && wasm
+// +build js,wasm
+
+// Example program that exposes the dsmr4p1 parser to JavaScript, so browser
+// based tools can parse pasted telegrams client-side. Build it using:
+//
+//	GOOS=js GOARCH=wasm go build -o dsmr4p1.wasm ./examples/wasm
+//
+// and load it using dsmr4p1.js (which needs wasm_exec.js from the Go
+// distribution).
+package main
+
+import (
+	"fmt"
+	"syscall/js"
+
+	"github.com/mhe/dsmr4p1"
+)
+
+// parse parses the telegram in args[0] and returns an object holding the
+// vendor and identifier, the timestamp, the values per OBIS code and the
+// validation error (if any).
+func parse(this js.Value, args []js.Value) (result interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = map[string]interface{}{"error": fmt.Sprint("malformed telegram: ", r)}
+		}
+	}()
+	if len(args) != 1 || args[0].Type() != js.TypeString {
+		return map[string]interface{}{"error": "expected a telegram string"}
+	}
+
+	t := dsmr4p1.Telegram(args[0].String())
+	r, err := t.Parse()
+	if err != nil {
+		return map[string]interface{}{"error": err.Error()}
+	}
+	values := make(map[string]interface{}, len(r))
+	for code, v := range r {
+		list := make([]interface{}, len(v))
+		for i, s := range v {
+			list[i] = s
+		}
+		values[code] = list
+	}
+
+	res := map[string]interface{}{
+		"values": values,
+	}
+	if id, err := t.Identifier(); err == nil {
+		res["vendor"] = id.Vendor
+		res["identifier"] = id.Ident
+	}
+	if v := r["0-0:1.0.0"]; len(v) > 0 {
+		if ts, err := dsmr4p1.ParseTimestamp(v[0]); err == nil {
+			res["timestamp"] = ts.Format("2006-01-02T15:04:05Z07:00")
+		}
+	}
+	// Pasted telegrams often lost their CRs, which breaks the CRC, so a
+	// validation error doesn't prevent returning the values.
+	if err := t.Validate(); err != nil {
+		res["error"] = err.Error()
+	}
+	return res
+}
+
+func main() {
+	js.Global().Set("dsmrParse", js.FuncOf(parse))
+	// Keep running, so the function remains available.
+	select {}
+}
@@ -0,0 +1,166 @@
+package dsmr4p1
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// FakeMeter is a PortOpener that simulates a smartmeter following a script of
+// telegrams, errors, delays and disconnects. It allows applications embedding
+// this library to test their handling of a meter deterministically:
+//
+//	m := new(dsmr4p1.FakeMeter).
+//		SendTelegram(t).
+//		Delay(time.Second).
+//		Disconnect().
+//		SendTelegram(t)
+//	r := dsmr4p1.NewPortReader(m, time.Millisecond)
+//	for t := range dsmr4p1.Poll(r) {
+//		// Receives both telegrams, then the channel is closed.
+//	}
+//
+// The ports opened by a FakeMeter share the script: a port continues where
+// the previous one stopped. Once the script is exhausted, reads return io.EOF
+// and OpenPort fails with ErrorPortGone, which stops a PortReader. The methods
+// building the script are safe to call while a port is read from.
+type FakeMeter struct {
+	mu       sync.Mutex
+	script   []fakeStep
+	openErrs []error
+}
+
+// fakeStep is a step of the script of a FakeMeter. Exactly one field is set.
+type fakeStep struct {
+	data       []byte
+	delay      time.Duration
+	err        error
+	disconnect bool
+}
+
+// Send adds raw data to the script, e.g. a partial or corrupted telegram.
+func (m *FakeMeter) Send(data []byte) *FakeMeter {
+	return m.add(fakeStep{data: append([]byte(nil), data...)})
+}
+
+// SendTelegram adds telegram t, with a correct CRC, to the script.
+func (m *FakeMeter) SendTelegram(t Telegram) *FakeMeter {
+	return m.add(fakeStep{data: t.WithUpdatedCRC()})
+}
+
+// Delay adds a pause of d to the script.
+func (m *FakeMeter) Delay(d time.Duration) *FakeMeter {
+	return m.add(fakeStep{delay: d})
+}
+
+// Fail adds a read error to the script.
+func (m *FakeMeter) Fail(err error) *FakeMeter {
+	return m.add(fakeStep{err: err})
+}
+
+// Disconnect adds a disconnect to the script: the port returns io.EOF from then
+// on, so it has to be reopened to continue.
+func (m *FakeMeter) Disconnect() *FakeMeter {
+	return m.add(fakeStep{disconnect: true})
+}
+
+// FailOpen makes the next call to OpenPort fail with err. Multiple calls queue
+// multiple failures.
+func (m *FakeMeter) FailOpen(err error) *FakeMeter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.openErrs = append(m.openErrs, err)
+	return m
+}
+
+func (m *FakeMeter) add(s fakeStep) *FakeMeter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.script = append(m.script, s)
+	return m
+}
+
+// OpenPort opens a port that reads the rest of the script, unless a failure
+// was queued using FailOpen. It returns ErrorPortGone once the script is
+// exhausted.
+func (m *FakeMeter) OpenPort() (Port, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.openErrs) > 0 {
+		err := m.openErrs[0]
+		m.openErrs = m.openErrs[1:]
+		return nil, err
+	}
+	if len(m.script) == 0 {
+		return nil, ErrorPortGone
+	}
+	return &fakePort{meter: m, closed: make(chan struct{})}, nil
+}
+
+// next pops the next step from the script, or returns false if the script is
+// exhausted. A data step is only popped once it has been read completely.
+func (m *FakeMeter) next(p []byte) (fakeStep, int, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.script) == 0 {
+		return fakeStep{}, 0, false
+	}
+	s := &m.script[0]
+	if s.data != nil {
+		n := copy(p, s.data)
+		if s.data = s.data[n:]; len(s.data) == 0 {
+			m.script = m.script[1:]
+		}
+		return fakeStep{}, n, true
+	}
+	step := *s
+	m.script = m.script[1:]
+	return step, 0, true
+}
+
+// fakePort is a Port opened by a FakeMeter.
+type fakePort struct {
+	meter *FakeMeter
+
+	once         sync.Once
+	closed       chan struct{}
+	disconnected bool
+}
+
+func (p *fakePort) Read(b []byte) (int, error) {
+	// Nothing of a data step can be read into an empty buffer.
+	if len(b) == 0 {
+		return 0, nil
+	}
+	for {
+		select {
+		case <-p.closed:
+			return 0, io.EOF
+		default:
+		}
+		if p.disconnected {
+			return 0, io.EOF
+		}
+		step, n, ok := p.meter.next(b)
+		switch {
+		case !ok:
+			return 0, io.EOF
+		case n > 0:
+			return n, nil
+		case step.err != nil:
+			return 0, step.err
+		case step.disconnect:
+			p.disconnected = true
+		case step.delay > 0:
+			select {
+			case <-p.closed:
+			case <-time.After(step.delay):
+			}
+		}
+	}
+}
+
+func (p *fakePort) Close() error {
+	p.once.Do(func() { close(p.closed) })
+	return nil
+}
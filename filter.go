@@ -0,0 +1,66 @@
+package dsmr4p1
+
+import (
+	"bytes"
+)
+
+// Filter returns a copy of the telegram that only contains the lines with the
+// given OBIS codes, e.g. to reduce the bandwidth and storage needed when only
+// a handful of fields are of interest. The header and the '!' line are always
+// kept. If the telegram includes a CRC trailer, the CRC is recomputed so the
+// result is still a valid frame.
+func (t Telegram) Filter(codes ...string) Telegram {
+	allowed := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		allowed[code] = true
+	}
+	return t.mapLines(func(code string, line []byte) []byte {
+		if !allowed[code] {
+			return nil
+		}
+		return line
+	})
+}
+
+// mapLines returns a copy of the telegram in which each data line is replaced
+// by the result of f, called with the OBIS code of the line and the line
+// without its line ending. Lines for which f returns nil are dropped. The
+// header and the '!' line are kept, and lines that don't start with an OBIS
+// code are treated as a continuation of the previous line. If the telegram
+// includes a CRC trailer, the CRC is recomputed.
+func (t Telegram) mapLines(f func(code string, line []byte) []byte) Telegram {
+	p := t.payload()
+	u := make(Telegram, 0, len(p))
+	keep := true
+	for n := 0; len(p) > 0; n++ {
+		i := bytes.IndexByte(p, '\n')
+		if i == -1 {
+			i = len(p) - 1
+		}
+		l := p[:i+1]
+		p = p[i+1:]
+		if n < 2 || len(p) == 0 {
+			u = append(u, l...)
+			continue
+		}
+
+		content := bytes.TrimRight(l, "\r\n")
+		j := bytes.IndexByte(content, '(')
+		if j <= 0 {
+			if keep {
+				u = append(u, l...)
+			}
+			continue
+		}
+		mapped := f(string(content[:j]), content)
+		if keep = mapped != nil; keep {
+			u = append(u, mapped...)
+			u = append(u, l[len(content):]...)
+		}
+	}
+
+	if len(bytes.TrimSpace(t[len(t.payload()):])) > 0 {
+		return u.WithUpdatedCRC()
+	}
+	return u
+}
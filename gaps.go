@@ -0,0 +1,66 @@
+package dsmr4p1
+
+import "time"
+
+// GapCounter estimates how many telegrams were missed, based on the timestamps
+// of the telegrams that were received and the interval at which the meter
+// emits them. This quantifies the reliability of the serial connection better
+// than counting CRC errors does, as it also covers telegrams that were lost
+// entirely. As it goes by the timestamps rather than the times of arrival, it
+// also works for bridges that batch telegrams (see Envelope.Burst). Feed it
+// telegrams using Add.
+type GapCounter struct {
+	// Interval is the interval at which the meter emits telegrams. Defaults to
+	// ten seconds (DSMR 4); DSMR 5 meters emit a telegram every second.
+	Interval time.Duration
+
+	last     time.Time
+	received uint64
+	missed   uint64
+}
+
+// Add counts telegram t, and the telegrams that were likely missed since the
+// previous one.
+func (g *GapCounter) Add(t Telegram) error {
+	_, ts, err := t.parseTimestamped()
+	if err != nil {
+		return err
+	}
+	interval := g.Interval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	g.received++
+	if !g.last.IsZero() {
+		g.missed += uint64(missedBetween(g.last, ts, interval))
+	}
+	if ts.After(g.last) {
+		g.last = ts
+	}
+	return nil
+}
+
+// missedBetween returns the number of telegrams that were likely missed
+// between telegrams with timestamps from and to.
+func missedBetween(from, to time.Time, interval time.Duration) int {
+	if !to.After(from) {
+		return 0
+	}
+	// Round, as the meter's timestamps only have a resolution of a second.
+	n := (to.Sub(from) + interval/2) / interval
+	if n <= 1 {
+		return 0
+	}
+	return int(n - 1)
+}
+
+// Received returns the number of telegrams counted.
+func (g *GapCounter) Received() uint64 {
+	return g.received
+}
+
+// Missed returns the estimated number of telegrams that were missed.
+func (g *GapCounter) Missed() uint64 {
+	return g.missed
+}
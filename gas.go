@@ -0,0 +1,173 @@
+package dsmr4p1
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// ErrorParseGasReading indicates that a gas reading does not have the expected
+// "(capture timestamp)(value*unit)" form.
+var ErrorParseGasReading = errors.New("error parsing gas reading")
+
+// ErrorParseGasProfile indicates that a gas profile does not have the expected
+// "(start)(status)(period)(count)(code)(unit)(value)..." form.
+var ErrorParseGasProfile = errors.New("error parsing gas profile")
+
+// GasReading is a reading of the gas meter at a point in time. As the gas
+// meter only sends a new reading every five minutes or every hour, Time is
+// typically well before the time of the telegram holding the reading.
+type GasReading struct {
+	Time  time.Time
+	Value Measurement // In m3.
+}
+
+// ParseGasReading parses the values of a gas reading (0-n:24.2.1), i.e. the
+// time of capture and the value, e.g. "(101209112500W)(12785.123*m3)".
+func ParseGasReading(values []string) (GasReading, error) {
+	if len(values) != 2 {
+		return GasReading{}, ErrorParseGasReading
+	}
+	captured, err := ParseTimestamp(values[0])
+	if err != nil {
+		return GasReading{}, err
+	}
+	value, err := ParseMeasurement(values[1])
+	if err != nil {
+		return GasReading{}, err
+	}
+	return GasReading{Time: captured, Value: value}, nil
+}
+
+// ParseGasProfile parses the values of a gas profile, i.e. the hourly values
+// that DSMR 3 and some DSMR 4 meters emit as "0-n:24.3.0", e.g.:
+//
+//	0-1:24.3.0(121030140000)(00)(60)(3)(0-1:24.2.1)(m3)(00001.001)(00001.002)(00001.003)
+//
+// The first reading was captured at the start time and each next one a period
+// (in minutes) later. Timestamps without DST indicator are taken to be in Dutch
+// time.
+func ParseGasProfile(values []string) ([]GasReading, error) {
+	if len(values) < 6 {
+		return nil, ErrorParseGasProfile
+	}
+	start, err := parseProfileTime(values[0])
+	if err != nil {
+		return nil, err
+	}
+	period, err := strconv.Atoi(values[2])
+	if err != nil {
+		return nil, ErrorParseGasProfile
+	}
+	count, err := strconv.Atoi(values[3])
+	if err != nil || count != len(values)-6 {
+		return nil, ErrorParseGasProfile
+	}
+	if values[5] != "m3" {
+		return nil, ErrorParseGasProfile
+	}
+
+	readings := make([]GasReading, count)
+	for i, v := range values[6:] {
+		value, err := ParseDecimal(v)
+		if err != nil {
+			return nil, ErrorParseGasProfile
+		}
+		readings[i] = GasReading{
+			Time:  start.Add(time.Duration(i*period) * time.Minute),
+			Value: Measurement{Value: value.Float64(), Unit: UnitM3, Precision: value.Scale},
+		}
+	}
+	return readings, nil
+}
+
+// parseProfileTime parses a timestamp with or without DST indicator.
+func parseProfileTime(timestamp string) (time.Time, error) {
+	if len(timestamp) != 12 {
+		return ParseTimestamp(timestamp)
+	}
+	loc, err := dutchLocation()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return parseClock([]byte(timestamp), loc)
+}
+
+// GasCapture is a distinct reading of the gas meter.
+type GasCapture struct {
+	// Time is the time at which the gas meter captured the reading. This is
+	// not the time of the telegram it was received in.
+	Time  time.Time
+	Value float64 // In m3.
+
+	// Delta and Interval hold the consumption and the time since the previous
+	// capture. Both are zero for the first capture.
+	Delta    float64
+	Interval time.Duration
+}
+
+// GasTracker keeps track of the gas readings in a stream of telegrams. Gas
+// meters only send a new reading every five minutes (DSMR 4.2 and later) or
+// every hour, whereas telegrams are emitted every 1-10 seconds. GasTracker
+// reports each reading only once, along with the consumption since the
+// previous reading, and the staleness of the latest reading. This avoids e.g.
+// plotting hours of flat readings. The zero value is ready to use.
+type GasTracker struct {
+	// Code is the OBIS code of the gas reading. Defaults to ObisGasDelivered,
+	// i.e. a gas meter on M-Bus channel 1.
+	Code string
+
+	last      GasCapture
+	seen      bool
+	telegramT time.Time
+}
+
+// Add processes telegram t. If it contains a new gas reading, that reading is
+// returned and the boolean is true.
+func (g *GasTracker) Add(t Telegram) (GasCapture, bool, error) {
+	r, ts, err := t.parseTimestamped()
+	if err != nil {
+		return GasCapture{}, false, err
+	}
+	g.telegramT = ts
+
+	code := g.Code
+	if code == "" {
+		code = ObisGasDelivered
+	}
+	values, ok := r[code]
+	if !ok {
+		return GasCapture{}, false, nil
+	}
+	reading, err := ParseGasReading(values)
+	if err != nil {
+		return GasCapture{}, false, err
+	}
+	captured, value := reading.Time, reading.Value.Value
+
+	if g.seen && !captured.After(g.last.Time) {
+		return GasCapture{}, false, nil
+	}
+	c := GasCapture{Time: captured, Value: value}
+	if g.seen {
+		c.Delta = value - g.last.Value
+		c.Interval = captured.Sub(g.last.Time)
+	}
+	g.last, g.seen = c, true
+	return c, true, nil
+}
+
+// Last returns the most recent gas reading. The boolean is false if there has
+// not been one yet.
+func (g *GasTracker) Last() (GasCapture, bool) {
+	return g.last, g.seen
+}
+
+// Staleness returns the age of the most recent gas reading at the time of the
+// most recent telegram.
+func (g *GasTracker) Staleness() time.Duration {
+	if !g.seen {
+		return 0
+	}
+	return g.telegramT.Sub(g.last.Time)
+}
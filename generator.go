@@ -0,0 +1,145 @@
+package dsmr4p1
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Generator generates structurally valid telegrams with pseudo-random values,
+// in a pseudo-random order, for property-based testing of consumers. It favors
+// edge cases such as maximal registers, timestamps around DST transitions and
+// long power failure logs. The same seed always yields the same telegrams.
+type Generator struct {
+	rand *rand.Rand
+}
+
+// NewGenerator returns a Generator using the given seed.
+func NewGenerator(seed int64) *Generator {
+	return &Generator{rand: rand.New(rand.NewSource(seed))}
+}
+
+// Telegram returns a new telegram, including a valid CRC trailer.
+func (g *Generator) Telegram() Telegram {
+	ts := g.timestamp()
+	lines := []string{
+		"1-3:0.2.8(50)",
+		"0-0:1.0.0(" + ts + ")",
+		"0-0:96.1.1(" + g.hex(g.rand.Intn(17)) + ")",
+		"1-0:1.8.1(" + g.register() + "*kWh)",
+		"1-0:1.8.2(" + g.register() + "*kWh)",
+		"1-0:2.8.1(" + g.register() + "*kWh)",
+		"1-0:2.8.2(" + g.register() + "*kWh)",
+		fmt.Sprintf("0-0:96.14.0(%04d)", 1+g.rand.Intn(2)),
+		"1-0:1.7.0(" + g.power() + "*kW)",
+		"1-0:2.7.0(" + g.power() + "*kW)",
+		fmt.Sprintf("0-0:96.7.21(%05d)", g.rand.Intn(100000)),
+		fmt.Sprintf("0-0:96.7.9(%05d)", g.rand.Intn(100000)),
+		g.failureLog(),
+		"0-0:96.13.0(" + g.hex(g.rand.Intn(1025)) + ")",
+		"0-1:24.1.0(003)",
+		"0-1:96.1.0(" + g.hex(g.rand.Intn(17)) + ")",
+		"0-1:24.2.1(" + g.timestamp() + ")(" + g.register() + "*m3)",
+	}
+	for _, phase := range []int{21, 41, 61} {
+		lines = append(lines,
+			fmt.Sprintf("1-0:%d.7.0(%03d*A)", phase+10, g.rand.Intn(1000)),
+			fmt.Sprintf("1-0:%d.7.0(%05.1f*V)", phase+11, 200+60*g.rand.Float64()),
+			fmt.Sprintf("1-0:%d.7.0(%s*kW)", phase, g.power()),
+			fmt.Sprintf("1-0:%d.7.0(%s*kW)", phase+1, g.power()),
+		)
+	}
+	g.rand.Shuffle(len(lines), func(i, j int) { lines[i], lines[j] = lines[j], lines[i] })
+
+	t := "/" + g.vendor() + "5\\2" + g.hex(4) + "\r\n\r\n" + strings.Join(lines, "\r\n") + "\r\n!"
+	return Telegram(t).WithUpdatedCRC()
+}
+
+// vendor returns a random three letter manufacturer flag.
+func (g *Generator) vendor() string {
+	b := make([]byte, 3)
+	for i := range b {
+		b[i] = byte('A' + g.rand.Intn(26))
+	}
+	return string(b)
+}
+
+// hex returns a random octet string of n bytes.
+func (g *Generator) hex(n int) string {
+	b := make([]byte, n)
+	g.rand.Read(b)
+	return fmt.Sprintf("%X", b)
+}
+
+// register returns the value of a cumulative register, which is maximal or
+// zero every now and then.
+func (g *Generator) register() string {
+	switch g.rand.Intn(10) {
+	case 0:
+		return "999999.999"
+	case 1:
+		return "000000.000"
+	}
+	return fmt.Sprintf("%010.3f", float64(g.rand.Intn(1e9))/1000)
+}
+
+// power returns a power in kW, which is zero every now and then.
+func (g *Generator) power() string {
+	if g.rand.Intn(5) == 0 {
+		return "00.000"
+	}
+	return fmt.Sprintf("%06.3f", float64(g.rand.Intn(1e5))/1000)
+}
+
+// timestamp returns a timestamp, which lies around a DST transition every now
+// and then.
+func (g *Generator) timestamp() string {
+	loc, err := dutchLocation()
+	if err != nil {
+		loc = winterTimezone
+	}
+	year := 2000 + g.rand.Intn(60)
+	var t time.Time
+	switch g.rand.Intn(4) {
+	case 0, 1:
+		// DST starts and ends at 1:00 UTC on the last Sunday of March and
+		// October respectively. When it ends, the clock shows the hour from 2:00
+		// to 3:00 twice.
+		month := time.March
+		if g.rand.Intn(2) == 0 {
+			month = time.October
+		}
+		t = lastSunday(year, month).Add(time.Duration(g.rand.Intn(2*3600)) * time.Second)
+	default:
+		t = time.Date(year, 1, 1, 0, 0, 0, 0, loc).Add(time.Duration(g.rand.Int63n(int64(365 * 24 * time.Hour))))
+	}
+	return formatTimestamp(t.In(loc))
+}
+
+// lastSunday returns midnight UTC on the last Sunday of the month.
+func lastSunday(year int, month time.Month) time.Time {
+	t := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+	return t.AddDate(0, 0, -int(t.Weekday()))
+}
+
+// failureLog returns a power failure log with up to 10 entries.
+func (g *Generator) failureLog() string {
+	n := g.rand.Intn(11)
+	var b strings.Builder
+	fmt.Fprintf(&b, "1-0:99.97.0(%d)(0-0:96.7.19)", n)
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "(%s)(%010d*s)", g.timestamp(), g.rand.Int63n(1e10))
+	}
+	return b.String()
+}
+
+// formatTimestamp formats t in the YYMMDDhhmmssX format of the timestamps in
+// telegrams. t should be in Dutch time.
+func formatTimestamp(t time.Time) string {
+	dst := "W"
+	if zone, _ := t.Zone(); zone == "CEST" {
+		dst = "S"
+	}
+	return t.Format("060102150405") + dst
+}
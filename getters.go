@@ -0,0 +1,85 @@
+package dsmr4p1
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorNotPresent indicates that a telegram doesn't contain a requested OBIS
+// code, i.e. the meter doesn't report that field.
+var ErrorNotPresent = errors.New("OBIS code not present in telegram")
+
+// measurement returns the value in the last bracket of an OBIS code.
+func (t Telegram) measurement(code string) (Measurement, error) {
+	values, ok := t.Get(code)
+	if !ok {
+		return Measurement{}, fmt.Errorf("%w: %s", ErrorNotPresent, code)
+	}
+	m, err := ParseMeasurement(values[len(values)-1])
+	if err != nil {
+		return Measurement{}, fmt.Errorf("%s: %w", code, err)
+	}
+	return m, nil
+}
+
+// PowerDelivered returns the actual power delivered to the client (1-0:1.7.0).
+func (t Telegram) PowerDelivered() (Measurement, error) {
+	return t.measurement(ObisPowerDelivered)
+}
+
+// PowerReceived returns the actual power delivered by the client (1-0:2.7.0).
+func (t Telegram) PowerReceived() (Measurement, error) {
+	return t.measurement(ObisPowerReceived)
+}
+
+// EnergyDeliveredTariff returns the register of the energy delivered to the
+// client in tariff n (1-0:1.8.n).
+func (t Telegram) EnergyDeliveredTariff(n int) (Measurement, error) {
+	return t.measurement(fmt.Sprintf("1-0:1.8.%d", n))
+}
+
+// EnergyReceivedTariff returns the register of the energy delivered by the
+// client in tariff n (1-0:2.8.n).
+func (t Telegram) EnergyReceivedTariff(n int) (Measurement, error) {
+	return t.measurement(fmt.Sprintf("1-0:2.8.%d", n))
+}
+
+// VoltageL1 returns the instantaneous voltage of L1 (1-0:32.7.0).
+func (t Telegram) VoltageL1() (Measurement, error) {
+	return t.measurement(ObisVoltageL1)
+}
+
+// VoltageL2 returns the instantaneous voltage of L2 (1-0:52.7.0).
+func (t Telegram) VoltageL2() (Measurement, error) {
+	return t.measurement(ObisVoltageL2)
+}
+
+// VoltageL3 returns the instantaneous voltage of L3 (1-0:72.7.0).
+func (t Telegram) VoltageL3() (Measurement, error) {
+	return t.measurement(ObisVoltageL3)
+}
+
+// CurrentL1 returns the instantaneous current of L1 (1-0:31.7.0).
+func (t Telegram) CurrentL1() (Measurement, error) {
+	return t.measurement(ObisCurrentL1)
+}
+
+// CurrentL2 returns the instantaneous current of L2 (1-0:51.7.0).
+func (t Telegram) CurrentL2() (Measurement, error) {
+	return t.measurement(ObisCurrentL2)
+}
+
+// CurrentL3 returns the instantaneous current of L3 (1-0:71.7.0).
+func (t Telegram) CurrentL3() (Measurement, error) {
+	return t.measurement(ObisCurrentL3)
+}
+
+// GasDelivered returns the last reading of the gas meter (0-n:24.2.1), on
+// whichever M-Bus channel it is. Use Decode for its time of capture.
+func (t Telegram) GasDelivered() (Measurement, error) {
+	channel := gasChannel(t.Get)
+	if channel == 0 {
+		return Measurement{}, fmt.Errorf("%w: %s", ErrorNotPresent, ObisGasDelivered)
+	}
+	return t.measurement(fmt.Sprintf("0-%d:24.2.1", channel))
+}
@@ -0,0 +1,128 @@
+package dsmr4p1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+)
+
+// GoldenDiff is a difference between the parser output for a capture and the
+// expected (golden) output. A telegram missing from either side is reported
+// with an empty Code, and the OBIS codes of the telegram that is present as Got
+// or Want. A telegram of the capture that can't be parsed is reported with its
+// error as Err, and the OBIS codes of the golden output (if any) as Want.
+type GoldenDiff struct {
+	Telegram int // Index of the telegram in the capture.
+	Code     string
+	Got      []string
+	Want     []string
+	Err      error
+}
+
+func (d GoldenDiff) String() string {
+	switch {
+	case d.Err != nil:
+		return fmt.Sprintf("telegram %d: %v", d.Telegram, d.Err)
+	case d.Code == "" && d.Want == nil:
+		return fmt.Sprintf("telegram %d: unexpected telegram", d.Telegram)
+	case d.Code == "":
+		return fmt.Sprintf("telegram %d: missing telegram", d.Telegram)
+	}
+	return fmt.Sprintf("telegram %d: %s: got %q, want %q", d.Telegram, d.Code, d.Got, d.Want)
+}
+
+// ParseCapture reads all telegrams with a valid CRC from a raw capture and
+// parses them.
+func ParseCapture(capture io.Reader) ([]map[string][]string, error) {
+	results, errs := parseCapture(capture)
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("telegram %d: %w", i, err)
+		}
+	}
+	return results, nil
+}
+
+// parseCapture reads all telegrams with a valid CRC from a raw capture and
+// parses them. The error of a telegram that can't be parsed is stored at its
+// index in errs, and its result is nil.
+func parseCapture(capture io.Reader) (results []map[string][]string, errs []error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for t := range new(Poller).Poll(ctx, capture) {
+		r, err := t.Parse()
+		results = append(results, r)
+		errs = append(errs, err)
+	}
+	return results, errs
+}
+
+// WriteGolden writes the parser output for a raw capture as JSON to w, i.e. a
+// list with an object per telegram that maps the OBIS codes to their values.
+// Review the output before using it as the golden file for CompareGolden.
+func WriteGolden(w io.Writer, capture io.Reader) error {
+	results, err := ParseCapture(capture)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
+
+// CompareGolden parses a raw capture and compares the result with the golden
+// JSON output, as written by WriteGolden. This allows captures of exotic
+// meters to serve as regression tests of the parser:
+//
+//	diffs, err := dsmr4p1.CompareGolden(capture, golden)
+//	for _, d := range diffs {
+//		t.Error(d)
+//	}
+func CompareGolden(capture, golden io.Reader) ([]GoldenDiff, error) {
+	got, errs := parseCapture(capture)
+	var want []map[string][]string
+	if err := json.NewDecoder(golden).Decode(&want); err != nil {
+		return nil, err
+	}
+
+	var diffs []GoldenDiff
+	for i := 0; i < len(got) || i < len(want); i++ {
+		switch {
+		case i < len(got) && errs[i] != nil:
+			d := GoldenDiff{Telegram: i, Err: errs[i]}
+			if i < len(want) {
+				d.Want = sortedCodes(want[i], nil)
+			}
+			diffs = append(diffs, d)
+		case i >= len(want):
+			diffs = append(diffs, GoldenDiff{Telegram: i, Got: sortedCodes(got[i], nil)})
+		case i >= len(got):
+			diffs = append(diffs, GoldenDiff{Telegram: i, Want: sortedCodes(want[i], nil)})
+		default:
+			for _, code := range sortedCodes(got[i], want[i]) {
+				if !reflect.DeepEqual(got[i][code], want[i][code]) {
+					diffs = append(diffs, GoldenDiff{Telegram: i, Code: code, Got: got[i][code], Want: want[i][code]})
+				}
+			}
+		}
+	}
+	return diffs, nil
+}
+
+// sortedCodes returns the OBIS codes in a and b, sorted.
+func sortedCodes(a, b map[string][]string) []string {
+	codes := make([]string, 0, len(a))
+	for code := range a {
+		codes = append(codes, code)
+	}
+	for code := range b {
+		if _, ok := a[code]; !ok {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	return codes
+}
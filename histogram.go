@@ -0,0 +1,114 @@
+package dsmr4p1
+
+import (
+	"math"
+	"time"
+)
+
+// DefaultPowerBuckets are the bucket upper bounds (in W) used by a
+// PowerHistogram without explicit buckets.
+var DefaultPowerBuckets = []float64{100, 250, 500, 1000, 2000, 3000, 5000, 8000, 12000}
+
+// PowerHistogram keeps a rolling histogram of the instantaneous power delivered
+// to the client (1-0:1.7.0). Unlike the current value, it allows for
+// percentile-based insights such as the P95 load. Feed it telegrams using Add.
+type PowerHistogram struct {
+	// Buckets holds the upper bounds (in W) of the buckets, in increasing
+	// order. Larger values are counted in an additional overflow bucket. If nil,
+	// DefaultPowerBuckets is used. Don't change it after the first call to Add.
+	Buckets []float64
+
+	// Window limits the histogram to the values in the given period before the
+	// most recent telegram. Zero means no limit.
+	Window time.Duration
+
+	slots []histogramSlot
+}
+
+// Samples are counted per minute, so old ones can be dropped from the window
+// without keeping each individual sample.
+type histogramSlot struct {
+	minute time.Time
+	counts []uint64
+}
+
+// Add adds the power in telegram t to the histogram.
+func (h *PowerHistogram) Add(t Telegram) error {
+	r, ts, err := t.parseTimestamped()
+	if err != nil {
+		return err
+	}
+	if len(r[ObisPowerDelivered]) == 0 {
+		return nil
+	}
+	power, _, err := ParseValueWithUnit(r[ObisPowerDelivered][0])
+	if err != nil {
+		return err
+	}
+
+	buckets := h.buckets()
+	minute := ts.Truncate(time.Minute)
+	if n := len(h.slots); n == 0 || h.slots[n-1].minute.Before(minute) {
+		h.slots = append(h.slots, histogramSlot{minute: minute, counts: make([]uint64, len(buckets)+1)})
+	}
+	slot := h.slots[len(h.slots)-1]
+	i := 0
+	for i < len(buckets) && power > buckets[i] {
+		i++
+	}
+	slot.counts[i]++
+
+	if h.Window > 0 {
+		start := ts.Add(-h.Window)
+		j := 0
+		for j < len(h.slots) && h.slots[j].minute.Add(time.Minute).Before(start) {
+			j++
+		}
+		h.slots = h.slots[j:]
+	}
+	return nil
+}
+
+// Counts returns the number of samples per bucket. The last count is that of
+// the overflow bucket.
+func (h *PowerHistogram) Counts() []uint64 {
+	counts := make([]uint64, len(h.buckets())+1)
+	for _, s := range h.slots {
+		for i, c := range s.counts {
+			counts[i] += c
+		}
+	}
+	return counts
+}
+
+// Quantile returns an estimate of the q-quantile (0 <= q <= 1) of the power,
+// i.e. the upper bound of the bucket in which it falls. It returns +Inf if it
+// falls in the overflow bucket, and NaN if there are no samples.
+func (h *PowerHistogram) Quantile(q float64) float64 {
+	counts := h.Counts()
+	var total uint64
+	for _, c := range counts {
+		total += c
+	}
+	if total == 0 {
+		return math.NaN()
+	}
+	buckets := h.buckets()
+	rank := q * float64(total)
+	var cum uint64
+	for i, c := range counts[:len(buckets)] {
+		cum += c
+		// Skip empty buckets, which e.g. the 0-quantile would fall in.
+		if c > 0 && float64(cum) >= rank {
+			return buckets[i]
+		}
+	}
+	return math.Inf(1)
+}
+
+func (h *PowerHistogram) buckets() []float64 {
+	if h.Buckets == nil {
+		return DefaultPowerBuckets
+	}
+	return h.Buckets
+}
@@ -0,0 +1,181 @@
+package dsmr4p1
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Hub distributes telegrams to any number of subscribers and keeps the most
+// recent telegrams around. It is safe for concurrent use. Typical use:
+//
+//	hub := dsmr4p1.NewHub(360)
+//	go hub.Run(dsmr4p1.Poll(input))
+type Hub struct {
+	mu      sync.Mutex
+	history *Ring
+	latest  Telegram
+	subs    map[*subscription]struct{}
+	closed  bool
+	done    chan struct{}
+}
+
+type subscription struct {
+	ch     chan Telegram
+	filter func(Telegram) bool
+}
+
+// SubscribeOptions configures a subscription to a Hub.
+type SubscribeOptions struct {
+	// Filter, if not nil, selects which telegrams are delivered.
+	Filter func(Telegram) bool
+
+	// Buffer is the number of telegrams buffered for the subscriber. Telegrams
+	// that arrive while the buffer is full are dropped for this subscriber, so a
+	// slow subscriber never holds up the others. Defaults to 1.
+	Buffer int
+}
+
+// NewHub returns a Hub that keeps the last historySize telegrams. A hub without
+// history (a historySize of zero or less) still keeps the latest telegram.
+func NewHub(historySize int) *Hub {
+	if historySize < 0 {
+		historySize = 0
+	}
+	return NewHubWithHistory(NewRing(historySize))
+}
+
+// NewHubWithHistory returns a Hub that keeps its history in the given Ring,
+// allowing e.g. its MaxAge to be set. The Ring should not be used directly
+// afterwards.
+func NewHubWithHistory(history *Ring) *Hub {
+	return &Hub{
+		history: history,
+		subs:    make(map[*subscription]struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Run publishes all telegrams received from ch, typically the channel returned
+// by Poll. When ch is closed, the hub is closed as well.
+func (h *Hub) Run(ch <-chan Telegram) {
+	for t := range ch {
+		h.Publish(t)
+	}
+	h.Close()
+}
+
+// Publish stores t as the latest telegram and delivers it to the subscribers.
+// The filters of the subscribers are called without holding the lock of the
+// hub, so they may e.g. call Latest.
+func (h *Hub) Publish(t Telegram) {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return
+	}
+	h.latest = t
+	h.history.Add(t, time.Now())
+	subs := make([]*subscription, 0, len(h.subs))
+	for s := range h.subs {
+		subs = append(subs, s)
+	}
+	h.mu.Unlock()
+
+	selected := subs[:0]
+	for _, s := range subs {
+		if s.filter == nil || s.filter(t) {
+			selected = append(selected, s)
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, s := range selected {
+		// The subscription may have ended (and its channel been closed) while
+		// filtering.
+		if _, ok := h.subs[s]; !ok {
+			continue
+		}
+		select {
+		case s.ch <- t:
+		default:
+		}
+	}
+}
+
+// Close closes the channels of all subscribers. Telegrams published afterwards
+// are ignored.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	h.closed = true
+	close(h.done)
+	for s := range h.subs {
+		close(s.ch)
+		delete(h.subs, s)
+	}
+}
+
+// Latest returns the most recently published telegram. The boolean is false if
+// no telegram has been published yet.
+func (h *Hub) Latest() (Telegram, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.latest, h.latest != nil
+}
+
+// History returns (at most) the last n telegrams, oldest first. It returns no
+// telegrams for a negative n.
+func (h *Hub) History(n int) []Telegram {
+	if n < 0 {
+		n = 0
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.history.Last(n)
+}
+
+// HistorySince returns the telegrams published at or after t, oldest first, as
+// far as they are still kept.
+func (h *Hub) HistorySince(t time.Time) []Telegram {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.history.Since(t)
+}
+
+// Subscribe returns a channel on which published telegrams are delivered. The
+// channel is closed when ctx is cancelled or the hub is closed.
+func (h *Hub) Subscribe(ctx context.Context, opts SubscribeOptions) <-chan Telegram {
+	buffer := opts.Buffer
+	if buffer <= 0 {
+		buffer = 1
+	}
+	s := &subscription{ch: make(chan Telegram, buffer), filter: opts.Filter}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(s.ch)
+		return s.ch
+	}
+	h.subs[s] = struct{}{}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-h.done:
+			return
+		}
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.subs[s]; ok {
+			close(s.ch)
+			delete(h.subs, s)
+		}
+	}()
+	return s.ch
+}
@@ -0,0 +1,144 @@
+package dsmr4p1
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// The registers with the electricity received from the client, per tariff.
+var receivedTariffCodes = map[int]string{
+	1: ObisElectricityReceivedTariff1,
+	2: ObisElectricityReceivedTariff2,
+}
+
+// InvoiceTotals holds the totals of a billing period.
+type InvoiceTotals struct {
+	Delivered map[int]float64 // Per tariff, in Wh.
+	Received  map[int]float64 // Per tariff, in Wh.
+	Gas       float64         // In m3.
+}
+
+// InvoiceDiscrepancy is a difference between a measured and an invoiced total.
+// Code is the OBIS code of the register involved.
+type InvoiceDiscrepancy struct {
+	Code     string
+	Measured float64
+	Invoiced float64
+}
+
+func (d InvoiceDiscrepancy) String() string {
+	return fmt.Sprintf("%s: measured %g, invoiced %g", d.Code, d.Measured, d.Invoiced)
+}
+
+// InvoiceReconciler aggregates the register deltas of a billing period, so they
+// can be compared against the totals on an invoice. Feed it the telegrams of
+// (at least) the period using Add.
+type InvoiceReconciler struct {
+	// From and To delimit the billing period.
+	From, To time.Time
+
+	// GasCode is the OBIS code of the gas reading. Defaults to ObisGasDelivered.
+	GasCode string
+
+	first, last         map[string]float64
+	firstTime, lastTime time.Time
+}
+
+// Add processes telegram t. Telegrams outside the billing period are ignored.
+func (c *InvoiceReconciler) Add(t Telegram) error {
+	r, ts, err := t.parseTimestamped()
+	if err != nil {
+		return err
+	}
+	if ts.Before(c.From) || ts.After(c.To) {
+		return nil
+	}
+	if c.first == nil {
+		c.first = make(map[string]float64)
+		c.last = make(map[string]float64)
+	}
+	if c.firstTime.IsZero() || ts.Before(c.firstTime) {
+		c.firstTime = ts
+	}
+	if ts.After(c.lastTime) {
+		c.lastTime = ts
+	}
+
+	codes := []string{c.gasCode()}
+	for _, code := range deliveredTariffCodes {
+		codes = append(codes, code)
+	}
+	for _, code := range receivedTariffCodes {
+		codes = append(codes, code)
+	}
+	for _, code := range codes {
+		v, _, ok := numericValue(r[code])
+		if !ok {
+			continue
+		}
+		// Registers only increase, so the lowest and highest value are the ones
+		// at the start and end of the period, even if telegrams arrive out of
+		// order.
+		if first, seen := c.first[code]; !seen || v < first {
+			c.first[code] = v
+		}
+		if v > c.last[code] {
+			c.last[code] = v
+		}
+	}
+	return nil
+}
+
+func (c *InvoiceReconciler) gasCode() string {
+	if c.GasCode == "" {
+		return ObisGasDelivered
+	}
+	return c.GasCode
+}
+
+// Covered returns the part of the billing period covered by telegrams.
+// Consumption before the first and after the last telegram is not measured.
+func (c *InvoiceReconciler) Covered() (from, to time.Time) {
+	return c.firstTime, c.lastTime
+}
+
+// Measured returns the totals measured in the billing period.
+func (c *InvoiceReconciler) Measured() InvoiceTotals {
+	m := InvoiceTotals{Delivered: make(map[int]float64), Received: make(map[int]float64)}
+	for tariff, code := range deliveredTariffCodes {
+		if _, ok := c.first[code]; ok {
+			m.Delivered[tariff] = c.last[code] - c.first[code]
+		}
+	}
+	for tariff, code := range receivedTariffCodes {
+		if _, ok := c.first[code]; ok {
+			m.Received[tariff] = c.last[code] - c.first[code]
+		}
+	}
+	m.Gas = c.last[c.gasCode()] - c.first[c.gasCode()]
+	return m
+}
+
+// Reconcile compares the measured totals against the totals on an invoice and
+// returns the discrepancies, ordered by OBIS code. A relative difference of up
+// to tolerance (e.g. 0.01 for 1%) is accepted.
+func (c *InvoiceReconciler) Reconcile(invoice InvoiceTotals, tolerance float64) []InvoiceDiscrepancy {
+	m := c.Measured()
+	var discrepancies []InvoiceDiscrepancy
+	check := func(code string, measured, invoiced float64) {
+		if math.Abs(measured-invoiced) > tolerance*math.Max(math.Abs(measured), math.Abs(invoiced)) {
+			discrepancies = append(discrepancies, InvoiceDiscrepancy{code, measured, invoiced})
+		}
+	}
+	for tariff, code := range deliveredTariffCodes {
+		check(code, m.Delivered[tariff], invoice.Delivered[tariff])
+	}
+	for tariff, code := range receivedTariffCodes {
+		check(code, m.Received[tariff], invoice.Received[tariff])
+	}
+	check(c.gasCode(), m.Gas, invoice.Gas)
+	sort.Slice(discrepancies, func(i, j int) bool { return discrepancies[i].Code < discrepancies[j].Code })
+	return discrepancies
+}
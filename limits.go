@@ -0,0 +1,75 @@
+package dsmr4p1
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrorTooLarge indicates that a telegram exceeds the limits for untrusted
+	// input.
+	ErrorTooLarge = errors.New("telegram exceeds limits")
+	// ErrorNonPrintable indicates that a telegram contains a byte other than
+	// printable ASCII, CR or LF.
+	ErrorNonPrintable = errors.New("telegram contains non-printable byte")
+)
+
+// Limits hardens the handling of telegrams from untrusted sources, e.g.
+// received over the network rather than from a serial line. It caps the size
+// of the telegrams and rejects non-printable bytes, which bounds both the
+// allocations and the time of parsing, so parsing needs no timeout. Zero fields
+// take their defaults, which are generous for any real meter.
+type Limits struct {
+	MaxSize   int // In bytes, including the CRC trailer. Defaults to 8192.
+	MaxLines  int // Defaults to 256.
+	MaxValues int // The number of values, i.e. brackets. Defaults to 1024.
+}
+
+func (l *Limits) maxSize() int {
+	if l.MaxSize <= 0 {
+		return 8192
+	}
+	return l.MaxSize
+}
+
+// Check checks telegram t against the limits in a single pass, without
+// allocating. The returned error wraps ErrorTooLarge or ErrorNonPrintable.
+func (l *Limits) Check(t Telegram) error {
+	maxLines, maxValues := l.MaxLines, l.MaxValues
+	if maxLines <= 0 {
+		maxLines = 256
+	}
+	if maxValues <= 0 {
+		maxValues = 1024
+	}
+	if len(t) > l.maxSize() {
+		return fmt.Errorf("%w: %d bytes", ErrorTooLarge, len(t))
+	}
+	lines, values := 0, 0
+	for i, c := range t {
+		switch {
+		case c == '\n':
+			lines++
+		case c == '(':
+			values++
+		case c == '\r' || ' ' <= c && c <= '~':
+		default:
+			return fmt.Errorf("%w: 0x%02x at %d", ErrorNonPrintable, c, i)
+		}
+	}
+	if lines > maxLines {
+		return fmt.Errorf("%w: %d lines", ErrorTooLarge, lines)
+	}
+	if values > maxValues {
+		return fmt.Errorf("%w: %d values", ErrorTooLarge, values)
+	}
+	return nil
+}
+
+// Parse checks telegram t against the limits and parses it.
+func (l *Limits) Parse(t Telegram) (map[string][]string, error) {
+	if err := l.Check(t); err != nil {
+		return nil, err
+	}
+	return t.Parse()
+}
@@ -0,0 +1,112 @@
+package dsmr4p1
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// DeviceType is the type of an M-Bus device (0-n:24.1.0). The values are
+// those of the M-Bus standard (EN 13757-3).
+type DeviceType int
+
+// The M-Bus device types used with DSMR meters.
+const (
+	DeviceUnknown     DeviceType = 0
+	DeviceElectricity DeviceType = 2
+	DeviceGas         DeviceType = 3
+	DeviceThermal     DeviceType = 4 // Heat or cold.
+	DeviceWater       DeviceType = 7
+)
+
+// String returns the name of the device type, e.g. "gas".
+func (d DeviceType) String() string {
+	switch d {
+	case DeviceUnknown:
+		return "unknown"
+	case DeviceElectricity:
+		return "electricity"
+	case DeviceGas:
+		return "gas"
+	case DeviceThermal:
+		return "thermal"
+	case DeviceWater:
+		return "water"
+	}
+	return fmt.Sprintf("DeviceType(%d)", int(d))
+}
+
+// MBusDevice is a device connected to an M-Bus channel of the meter, e.g. the
+// gas meter.
+type MBusDevice struct {
+	Channel     int        // M-Bus channel, 1-4.
+	Type        DeviceType // From 0-n:24.1.0, DeviceUnknown if not reported.
+	EquipmentID string     // Decoded from 0-n:96.1.0, empty if not reported.
+
+	// Time is the time at which the device captured Reading (0-n:24.2.1),
+	// which may lag the time of the telegram. Both are zero if the device
+	// reported no reading.
+	Time    time.Time
+	Reading Measurement
+}
+
+// MBusDevices returns the M-Bus devices in telegram t, ordered by channel, so
+// consumers don't have to guess which channel e.g. the gas meter is on.
+func MBusDevices(t Telegram) ([]MBusDevice, error) {
+	r, err := t.Parse()
+	if err != nil {
+		return nil, err
+	}
+	return mbusDevices(r)
+}
+
+// isMBusCode reports whether code is one of the codes of an M-Bus channel
+// that mbusDevices decodes.
+func isMBusCode(code string) bool {
+	c, err := ParseObisCode(code)
+	if err != nil || c.Medium != 0 || c.Channel < 1 || c.Channel > 4 {
+		return false
+	}
+	switch c.WithChannel(1).String() {
+	case ObisMBusDeviceType, ObisMBusEquipmentID, ObisGasDelivered:
+		return true
+	}
+	return false
+}
+
+// mbusDevices returns the M-Bus devices in the parsed telegram r.
+func mbusDevices(r map[string][]string) ([]MBusDevice, error) {
+	var devices []MBusDevice
+	for channel := 1; channel <= 4; channel++ {
+		code := func(c string) string { return fmt.Sprintf("0-%d:%s", channel, c) }
+		deviceType, id, reading := r[code("24.1.0")], r[code("96.1.0")], r[code("24.2.1")]
+		if deviceType == nil && id == nil && reading == nil {
+			continue
+		}
+
+		d := MBusDevice{Channel: channel}
+		var err error
+		if len(deviceType) > 0 {
+			n, err := strconv.Atoi(deviceType[0])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", code("24.1.0"), err)
+			}
+			d.Type = DeviceType(n)
+		}
+		if len(id) > 0 {
+			if d.EquipmentID, err = ParseOctetString(id[0]); err != nil {
+				return nil, fmt.Errorf("%s: %w", code("96.1.0"), err)
+			}
+		}
+		if len(reading) == 2 {
+			if d.Time, err = ParseTimestamp(reading[0]); err != nil {
+				return nil, fmt.Errorf("%s: %w", code("24.2.1"), err)
+			}
+			if d.Reading, err = ParseMeasurement(reading[1]); err != nil {
+				return nil, fmt.Errorf("%s: %w", code("24.2.1"), err)
+			}
+		}
+		devices = append(devices, d)
+	}
+	return devices, nil
+}
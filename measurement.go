@@ -0,0 +1,121 @@
+package dsmr4p1
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Unit is the (base) unit of a Measurement.
+type Unit int
+
+// The units used by DSMR meters.
+const (
+	UnitNone Unit = iota
+	UnitW
+	UnitWh
+	UnitV
+	UnitA
+	UnitM3
+	UnitGJ
+	UnitS
+)
+
+var unitNames = [...]string{
+	UnitNone: "",
+	UnitW:    "W",
+	UnitWh:   "Wh",
+	UnitV:    "V",
+	UnitA:    "A",
+	UnitM3:   "m3",
+	UnitGJ:   "GJ",
+	UnitS:    "s",
+}
+
+// String returns the unit as it appears in telegrams, e.g. "Wh".
+func (u Unit) String() string {
+	if u < 0 || int(u) >= len(unitNames) {
+		return fmt.Sprintf("Unit(%d)", int(u))
+	}
+	return unitNames[u]
+}
+
+// parseUnit returns the Unit named s, where s is without "k" prefix.
+func parseUnit(s string) (Unit, bool) {
+	for u, name := range unitNames {
+		if name == s && name != "" {
+			return Unit(u), true
+		}
+	}
+	return UnitNone, false
+}
+
+// Measurement is a value with a unit, as found in telegrams.
+type Measurement struct {
+	// Value is the value in the base unit, i.e. without "k" prefix.
+	Value float64
+
+	// Unit is the base unit of the value.
+	Unit Unit
+
+	// Precision is the number of decimals of Value the meter reports. It is
+	// negative when the meter reports kilo units with less than three
+	// decimals, e.g. -2 for "016.1*kW".
+	Precision int
+}
+
+// ParseMeasurement parses a value with a unit, e.g. "001234.567*kWh". Unlike
+// ParseValueWithUnit, it keeps the precision and computes the value in the
+// base unit without rounding errors, e.g. 16100 for "016.1*kW". It returns an
+// error wrapping ErrorUnexpectedUnit for units other than those of Unit.
+func ParseMeasurement(input string) (Measurement, error) {
+	i := strings.IndexByte(input, '*')
+	if i < 0 {
+		return Measurement{}, ErrorParseValueWithUnit
+	}
+	number, unit := input[:i], input[i+1:]
+
+	var m Measurement
+	scale := 0
+	if strings.HasPrefix(unit, "k") {
+		unit = unit[1:]
+		scale = 3
+	}
+	u, ok := parseUnit(unit)
+	if !ok {
+		return Measurement{}, fmt.Errorf("%w: %q", ErrorUnexpectedUnit, input[i+1:])
+	}
+	m.Unit = u
+
+	d, err := ParseDecimal(number)
+	if err != nil {
+		return Measurement{}, err
+	}
+	m.Precision = d.Scale - scale
+	m.Value = Decimal{Units: d.Units, Scale: m.Precision}.Float64()
+	return m, nil
+}
+
+// Base returns the value in the base unit, e.g. in Wh.
+func (m Measurement) Base() float64 {
+	return m.Value
+}
+
+// Kilo returns the value in kilo units, e.g. in kWh.
+func (m Measurement) Kilo() float64 {
+	return m.Value / 1000
+}
+
+// String formats the measurement with the precision reported by the meter, in
+// kilo units if the precision is coarser than the base unit, e.g. "1234567 Wh"
+// or "16.1 kW".
+func (m Measurement) String() string {
+	if m.Precision < 0 {
+		return strconv.FormatFloat(m.Kilo(), 'f', m.Precision+3, 64) + " k" + m.Unit.String()
+	}
+	s := strconv.FormatFloat(m.Value, 'f', m.Precision, 64)
+	if m.Unit != UnitNone {
+		s += " " + m.Unit.String()
+	}
+	return s
+}
@@ -0,0 +1,47 @@
+package dsmr4p1
+
+import "encoding/hex"
+
+// Message is a message from the grid operator (DSO), as pushed to the meter.
+type Message struct {
+	Code string // The numeric message (0-0:96.13.1).
+	Text string // The text message (0-0:96.13.0).
+}
+
+// ParseOctetString decodes an octet string, i.e. the hexadecimal encoding used
+// for messages and equipment identifiers in telegrams.
+func ParseOctetString(s string) (string, error) {
+	b, err := hex.DecodeString(s)
+	return string(b), err
+}
+
+// MessageWatcher watches the messages from the grid operator in a stream of
+// telegrams, so users can be notified when a new message is pushed to their
+// meter. The zero value is ready to use.
+type MessageWatcher struct {
+	last Message
+}
+
+// Add returns the message in telegram t, and whether it differs from the
+// message in the previous telegram. The message being cleared also counts as a
+// change.
+func (w *MessageWatcher) Add(t Telegram) (Message, bool, error) {
+	r, err := t.Parse()
+	if err != nil {
+		return Message{}, false, err
+	}
+	var m Message
+	if v := r[ObisTextMessageCode]; len(v) > 0 {
+		if m.Code, err = ParseOctetString(v[0]); err != nil {
+			return Message{}, false, err
+		}
+	}
+	if v := r[ObisTextMessage]; len(v) > 0 {
+		if m.Text, err = ParseOctetString(v[0]); err != nil {
+			return Message{}, false, err
+		}
+	}
+	changed := m != w.last
+	w.last = m
+	return m, changed, nil
+}
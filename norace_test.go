@@ -0,0 +1,6 @@
+//go:build !race
+// +build !race
+
+package dsmr4p1
+
+const raceEnabled = false
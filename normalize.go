@@ -0,0 +1,61 @@
+package dsmr4p1
+
+// NormalizeNumbers undoes the mangling of numbers by some gateway devices that
+// re-emit telegrams: decimal commas between digits are replaced by dots, and
+// whitespace around the value and unit within brackets is removed, so e.g.
+// "( 01,193 * kW)" becomes "(01.193*kW)". The boolean reports whether anything
+// changed; if not, t itself is returned. A CRC trailer is left as is, so use
+// WithUpdatedCRC to turn the result back into a valid frame.
+func NormalizeNumbers(t Telegram) (Telegram, bool) {
+	var u Telegram // Only allocated once something changes.
+	in := false
+	for i, c := range t {
+		drop := false
+		switch {
+		case c == '(':
+			in = true
+		case c == ')':
+			in = false
+		case !in:
+		case c == ',' && i > 0 && isDigit(t[i-1]) && i+1 < len(t) && isDigit(t[i+1]):
+			c = '.'
+		case isBlank(c):
+			drop = blankAround(t, i)
+		}
+		if u == nil && (drop || c != t[i]) {
+			u = append(make(Telegram, 0, len(t)), t[:i]...)
+		}
+		if u != nil && !drop {
+			u = append(u, c)
+		}
+	}
+	if u == nil {
+		return t, false
+	}
+	return u, true
+}
+
+// blankAround reports whether the blank at t[i] is (part of) whitespace
+// following a '(' or '*', or preceding a ')' or '*'.
+func blankAround(t Telegram, i int) bool {
+	j := i
+	for j > 0 && isBlank(t[j-1]) {
+		j--
+	}
+	if j > 0 && (t[j-1] == '(' || t[j-1] == '*') {
+		return true
+	}
+	j = i
+	for j+1 < len(t) && isBlank(t[j+1]) {
+		j++
+	}
+	return j+1 < len(t) && (t[j+1] == ')' || t[j+1] == '*')
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isBlank(c byte) bool {
+	return c == ' ' || c == '\t'
+}
@@ -0,0 +1,150 @@
+package dsmr4p1
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorParseObisCode indicates that a string is not an OBIS code of the form
+// "A-B:C.D.E".
+var ErrorParseObisCode = errors.New("error parsing OBIS code")
+
+// The OBIS codes of the DSMR 4.x specification, grouped by category. The M-Bus
+// codes are those of channel 1, which is where the gas meter usually is.
+
+// Device information.
+const (
+	ObisVersion         = "1-3:0.2.8"   // Version information of the P1 output.
+	ObisTimestamp       = "0-0:1.0.0"   // Date and time of the telegram.
+	ObisEquipmentID     = "0-0:96.1.1"  // Equipment identifier (octet string).
+	ObisTextMessageCode = "0-0:96.13.1" // Numeric message (octet string).
+	ObisTextMessage     = "0-0:96.13.0" // Text message (octet string).
+)
+
+// Electricity.
+const (
+	ObisElectricityDeliveredTariff1 = "1-0:1.8.1"   // Meter reading delivered to client, tariff 1.
+	ObisElectricityDeliveredTariff2 = "1-0:1.8.2"   // Meter reading delivered to client, tariff 2.
+	ObisElectricityReceivedTariff1  = "1-0:2.8.1"   // Meter reading delivered by client, tariff 1.
+	ObisElectricityReceivedTariff2  = "1-0:2.8.2"   // Meter reading delivered by client, tariff 2.
+	ObisTariffIndicator             = "0-0:96.14.0" // Tariff indicator.
+	ObisPowerDelivered              = "1-0:1.7.0"   // Actual power delivered to client.
+	ObisPowerReceived               = "1-0:2.7.0"   // Actual power delivered by client.
+	ObisPowerThreshold              = "0-0:17.0.0"  // Threshold of the electricity limiter.
+	ObisSwitchPosition              = "0-0:96.3.10" // Switch position of the breaker.
+
+	ObisVoltageL1        = "1-0:32.7.0" // Instantaneous voltage L1.
+	ObisVoltageL2        = "1-0:52.7.0" // Instantaneous voltage L2.
+	ObisVoltageL3        = "1-0:72.7.0" // Instantaneous voltage L3.
+	ObisCurrentL1        = "1-0:31.7.0" // Instantaneous current L1.
+	ObisCurrentL2        = "1-0:51.7.0" // Instantaneous current L2.
+	ObisCurrentL3        = "1-0:71.7.0" // Instantaneous current L3.
+	ObisPowerDeliveredL1 = "1-0:21.7.0" // Instantaneous power delivered to client L1.
+	ObisPowerDeliveredL2 = "1-0:41.7.0" // Instantaneous power delivered to client L2.
+	ObisPowerDeliveredL3 = "1-0:61.7.0" // Instantaneous power delivered to client L3.
+	ObisPowerReceivedL1  = "1-0:22.7.0" // Instantaneous power delivered by client L1.
+	ObisPowerReceivedL2  = "1-0:42.7.0" // Instantaneous power delivered by client L2.
+	ObisPowerReceivedL3  = "1-0:62.7.0" // Instantaneous power delivered by client L3.
+)
+
+// Events.
+const (
+	ObisPowerFailures     = "0-0:96.7.21" // Number of power failures in any phase.
+	ObisLongPowerFailures = "0-0:96.7.9"  // Number of long power failures in any phase.
+	ObisPowerFailureLog   = "1-0:99.97.0" // Power failure event log.
+	ObisVoltageSagsL1     = "1-0:32.32.0" // Number of voltage sags in L1.
+	ObisVoltageSagsL2     = "1-0:52.32.0" // Number of voltage sags in L2.
+	ObisVoltageSagsL3     = "1-0:72.32.0" // Number of voltage sags in L3.
+	ObisVoltageSwellsL1   = "1-0:32.36.0" // Number of voltage swells in L1.
+	ObisVoltageSwellsL2   = "1-0:52.36.0" // Number of voltage swells in L2.
+	ObisVoltageSwellsL3   = "1-0:72.36.0" // Number of voltage swells in L3.
+)
+
+// Gas and other M-Bus devices (channel 1).
+const (
+	ObisMBusDeviceType    = "0-1:24.1.0" // Device type.
+	ObisMBusEquipmentID   = "0-1:96.1.0" // Equipment identifier (octet string).
+	ObisGasDelivered      = "0-1:24.2.1" // Last 5-minute (or hourly) value, with capture time.
+	ObisMBusValvePosition = "0-1:24.4.0" // Valve position of the gas meter.
+)
+
+// Codes of other national flavours (see Profiles) that are not part of DSMR 4.
+const (
+	ObisVersionEMUCS            = "0-0:96.1.4" // Version information of the e-MUCS P1 output.
+	ObisDeviceName              = "0-0:42.0.0" // Logical device name (Smarty).
+	ObisElectricityDelivered    = "1-0:1.8.0"  // Meter reading delivered to client, all tariffs.
+	ObisElectricityReceived     = "1-0:2.8.0"  // Meter reading delivered by client, all tariffs.
+	ObisAverageDemand           = "1-0:1.4.0"  // Average demand of the running quarter-hour (e-MUCS).
+	ObisMaximumDemand           = "1-0:1.6.0"  // Maximum demand of the month, with time (e-MUCS).
+	ObisMaximumDemandHistory    = "0-0:98.1.0" // Maximum demand of the previous months (e-MUCS).
+	ObisCurrentLimit            = "1-0:31.4.0" // Current limit of the breaker (e-MUCS).
+	ObisGasDeliveredUncorrected = "0-1:24.2.3" // Gas volume without temperature conversion, with capture time (e-MUCS).
+)
+
+// ObisCode is an OBIS code split into its components, e.g. "0-1:24.2.1" is
+// ObisCode{Medium: 0, Channel: 1, Quantity: 24, Processing: 2, Tariff: 1}.
+type ObisCode struct {
+	Medium     int // A: 0 for abstract objects, 1 for electricity.
+	Channel    int // B: 0 for the meter itself, 1-4 for M-Bus devices.
+	Quantity   int // C: the physical value, e.g. 1 for active power+.
+	Processing int // D: e.g. 7 for instantaneous, 8 for cumulative values.
+	Tariff     int // E: the tariff, or a further classification.
+}
+
+// ParseObisCode parses the string form of an OBIS code, e.g. "1-0:1.8.1".
+func ParseObisCode(s string) (ObisCode, error) {
+	const separators = "-:.."
+	var fields [5]int
+	for i := range fields {
+		if i > 0 {
+			if len(s) == 0 || s[0] != separators[i-1] {
+				return ObisCode{}, ErrorParseObisCode
+			}
+			s = s[1:]
+		}
+		n := 0
+		for n < len(s) && isDigit(s[n]) {
+			fields[i] = fields[i]*10 + int(s[n]-'0')
+			n++
+		}
+		if n == 0 || n > 3 {
+			return ObisCode{}, ErrorParseObisCode
+		}
+		s = s[n:]
+	}
+	if len(s) != 0 {
+		return ObisCode{}, ErrorParseObisCode
+	}
+	return ObisCode{fields[0], fields[1], fields[2], fields[3], fields[4]}, nil
+}
+
+// String returns the string form of the code, as used in telegrams.
+func (c ObisCode) String() string {
+	return fmt.Sprintf("%d-%d:%d.%d.%d", c.Medium, c.Channel, c.Quantity, c.Processing, c.Tariff)
+}
+
+// WithChannel returns the code with its channel replaced, e.g. to look up the
+// reading of an M-Bus device on another channel.
+func (c ObisCode) WithChannel(channel int) ObisCode {
+	c.Channel = channel
+	return c
+}
+
+// MatchesAnyChannel reports whether c and d are equal apart from their
+// channel, e.g. "0-1:24.2.1" and "0-3:24.2.1".
+func (c ObisCode) MatchesAnyChannel(d ObisCode) bool {
+	return c.WithChannel(0) == d.WithChannel(0)
+}
+
+// Less reports whether c sorts before d, comparing the components from left to
+// right.
+func (c ObisCode) Less(d ObisCode) bool {
+	a := [5]int{c.Medium, c.Channel, c.Quantity, c.Processing, c.Tariff}
+	b := [5]int{d.Medium, d.Channel, d.Quantity, d.Processing, d.Tariff}
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
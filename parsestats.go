@@ -0,0 +1,104 @@
+package dsmr4p1
+
+import (
+	"strings"
+	"sync"
+)
+
+// ParseFailure is a category of problems ParseStats counts.
+type ParseFailure string
+
+// The categories of problems ParseStats counts.
+const (
+	FailureMissingBracket ParseFailure = "missing bracket" // A line without '(' or not ending with ')'.
+	FailureUnknownCode    ParseFailure = "unknown code"    // An OBIS code neither described nor registered.
+	FailureBadUnit        ParseFailure = "bad unit"        // A value without the unit of its code.
+	FailureBadTimestamp   ParseFailure = "bad timestamp"   // A timestamp that doesn't parse.
+)
+
+// ParseStats counts the problems the parser runs into per category and OBIS
+// code, so telemetry of deployed meters shows which real-world meter behaviour
+// trips the parser most. Lines without OBIS code are counted under the empty
+// code. The zero value is ready to use, and it is safe for concurrent use.
+type ParseStats struct {
+	mu     sync.Mutex
+	counts map[ParseFailure]map[string]uint64
+}
+
+// Add counts the problems in telegram t.
+func (s *ParseStats) Add(t Telegram) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rest := string(t.payload())
+	// Skip the header and the empty line following it.
+	for i := 0; i < 2; i++ {
+		if _, rest, _ = cutLine(rest); rest == "" {
+			return
+		}
+	}
+	for first := true; ; first = false {
+		l, r, ok := cutLine(rest)
+		if !ok {
+			return
+		}
+		rest = r
+		i := strings.IndexByte(l, '(')
+		if i == 0 && !first {
+			// A continuation of the previous line (see Parse).
+			continue
+		}
+		if i == -1 || !strings.HasSuffix(l, ")") {
+			code := l
+			if i != -1 {
+				code = l[:i]
+			}
+			s.count(FailureMissingBracket, code)
+			continue
+		}
+		code, values := l[:i], appendValues(nil, l[i:])
+
+		d, ok := Describe(code)
+		if !ok {
+			if _, registered := lookupParser(code); !registered {
+				s.count(FailureUnknownCode, code)
+			}
+			continue
+		}
+		if strings.HasPrefix(d.Format, "TST") {
+			if _, err := ParseTimestamp(values[0]); err != nil {
+				s.count(FailureBadTimestamp, code)
+			}
+		}
+		if d.Unit != "" && !strings.HasPrefix(d.Format, "Buffer") {
+			_, unit, err := ParseValueWithUnit(values[len(values)-1])
+			if err != nil || unit != strings.TrimPrefix(d.Unit, "k") {
+				s.count(FailureBadUnit, code)
+			}
+		}
+	}
+}
+
+func (s *ParseStats) count(failure ParseFailure, code string) {
+	if s.counts == nil {
+		s.counts = make(map[ParseFailure]map[string]uint64)
+	}
+	if s.counts[failure] == nil {
+		s.counts[failure] = make(map[string]uint64)
+	}
+	s.counts[failure][code]++
+}
+
+// Counts returns a copy of the counters, per category and OBIS code.
+func (s *ParseStats) Counts() map[ParseFailure]map[string]uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := make(map[ParseFailure]map[string]uint64, len(s.counts))
+	for failure, codes := range s.counts {
+		counts[failure] = make(map[string]uint64, len(codes))
+		for code, n := range codes {
+			counts[failure][code] = n
+		}
+	}
+	return counts
+}
@@ -0,0 +1,54 @@
+package dsmr4p1
+
+import "time"
+
+// Peak is a maximum of the instantaneous power delivered to the client.
+type Peak struct {
+	Power float64   // In W.
+	Time  time.Time // The timestamp of the telegram with the peak.
+}
+
+// PeakTracker tracks the maximum instantaneous power delivered (1-0:1.7.0) per
+// day and per month, together with the time at which it occurred. Days and
+// months are in Dutch time, following the telegram timestamps. The zero value
+// is ready to use.
+type PeakTracker struct {
+	day   Peak
+	month Peak
+}
+
+// Add processes telegram t.
+func (p *PeakTracker) Add(t Telegram) error {
+	r, ts, err := t.parseTimestamped()
+	if err != nil {
+		return err
+	}
+	if len(r[ObisPowerDelivered]) == 0 {
+		return nil
+	}
+	power, _, err := ParseValueWithUnit(r[ObisPowerDelivered][0])
+	if err != nil {
+		return err
+	}
+
+	y, m, d := ts.Date()
+	py, pm, pd := p.day.Time.Date()
+	if y != py || m != pm || d != pd || power > p.day.Power {
+		p.day = Peak{Power: power, Time: ts}
+	}
+	py, pm, _ = p.month.Time.Date()
+	if y != py || m != pm || power > p.month.Power {
+		p.month = Peak{Power: power, Time: ts}
+	}
+	return nil
+}
+
+// Day returns the peak of the day of the most recent telegram.
+func (p *PeakTracker) Day() Peak {
+	return p.day
+}
+
+// Month returns the peak of the month of the most recent telegram.
+func (p *PeakTracker) Month() Peak {
+	return p.month
+}
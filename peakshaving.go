@@ -0,0 +1,72 @@
+package dsmr4p1
+
+import (
+	"fmt"
+	"time"
+)
+
+// PeakAdvice advises to reduce the load to avoid a new monthly peak of the
+// quarter-hourly average demand.
+type PeakAdvice struct {
+	Reduce    float64       // The reduction of the load needed, in W.
+	For       time.Duration // The remainder of the quarter-hour.
+	Projected float64       // The average demand of the quarter-hour if the load stays, in W.
+	Peak      float64       // The peak to stay below, in W.
+}
+
+func (a PeakAdvice) String() string {
+	return fmt.Sprintf("reduce load by %.0f W for %v to avoid a new monthly peak", a.Reduce, a.For.Round(time.Second))
+}
+
+// PeakShaver advises when to reduce the load to avoid a new monthly peak, for
+// meters that report the demand for a capacity tariff, such as Belgian meters:
+// the average demand of the running quarter-hour (1-0:1.4.0) and the maximum
+// demand of the month (1-0:1.6.0). Feed it telegrams using Add. The zero value
+// is ready to use.
+type PeakShaver struct {
+	// Floor is the peak below which peaks are not worth avoiding, as the
+	// capacity tariff charges a minimum. Defaults to 2500 W.
+	Floor float64
+
+	// Margin is kept below the peak, in W.
+	Margin float64
+}
+
+// Add processes telegram t. If the quarter-hour is heading for a new monthly
+// peak at the current load, the advice is returned and the boolean is true.
+func (s *PeakShaver) Add(t Telegram) (PeakAdvice, bool, error) {
+	r, ts, err := t.parseTimestamped()
+	if err != nil {
+		return PeakAdvice{}, false, err
+	}
+	average, _, ok := numericValue(r[ObisAverageDemand])
+	if !ok {
+		return PeakAdvice{}, false, nil
+	}
+	peak, _, _ := numericValue(r[ObisMaximumDemand])
+	power, _, _ := numericValue(r[ObisPowerDelivered])
+
+	floor := s.Floor
+	if floor <= 0 {
+		floor = 2500
+	}
+	if peak < floor {
+		peak = floor
+	}
+	peak -= s.Margin
+
+	const quarter = 15 * time.Minute
+	start := ts.Truncate(quarter)
+	elapsed := ts.Sub(start)
+	remaining := quarter - elapsed
+	projected := (average*elapsed.Hours() + power*remaining.Hours()) / quarter.Hours()
+	if projected <= peak || remaining <= 0 {
+		return PeakAdvice{}, false, nil
+	}
+	return PeakAdvice{
+		Reduce:    (projected - peak) * quarter.Hours() / remaining.Hours(),
+		For:       remaining,
+		Projected: projected,
+		Peak:      peak,
+	}, true, nil
+}
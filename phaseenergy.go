@@ -0,0 +1,65 @@
+package dsmr4p1
+
+import "time"
+
+// PhaseEnergyEstimator estimates the energy per phase, which meters don't
+// register, by integrating the instantaneous power per phase over time. The
+// estimates help diagnosing which phase drives the consumption, but drift from
+// the registers of the meter, e.g. over missed telegrams. The zero value is
+// ready to use.
+//
+// The counters are exported, so the estimator can be persisted (e.g. as JSON)
+// and restored to continue after a restart.
+type PhaseEnergyEstimator struct {
+	// Delivered and Received hold the estimated energy delivered to and by the
+	// client per phase (L1, L2, L3), in Wh.
+	Delivered [3]float64
+	Received  [3]float64
+
+	// MaxGap is the longest interval between telegrams that is integrated, so
+	// an outage of the connection doesn't count as constant power. Defaults to
+	// five minutes.
+	MaxGap time.Duration
+
+	last          time.Time
+	lastDelivered [3]float64
+	lastReceived  [3]float64
+}
+
+// Add processes telegram t.
+func (e *PhaseEnergyEstimator) Add(t Telegram) error {
+	r, ts, err := t.parseTimestamped()
+	if err != nil {
+		return err
+	}
+	var delivered, received [3]float64
+	for i := range delivered {
+		if len(r[phasePowerDeliveredCodes[i]]) > 0 {
+			if delivered[i], _, err = ParseValueWithUnit(r[phasePowerDeliveredCodes[i]][0]); err != nil {
+				return err
+			}
+		}
+		if len(r[phasePowerReceivedCodes[i]]) > 0 {
+			if received[i], _, err = ParseValueWithUnit(r[phasePowerReceivedCodes[i]][0]); err != nil {
+				return err
+			}
+		}
+	}
+
+	maxGap := e.MaxGap
+	if maxGap <= 0 {
+		maxGap = 5 * time.Minute
+	}
+	if !e.last.IsZero() && ts.After(e.last) && ts.Sub(e.last) <= maxGap {
+		// Trapezoidal integration of the power (W) over the interval.
+		hours := ts.Sub(e.last).Hours()
+		for i := range delivered {
+			e.Delivered[i] += (e.lastDelivered[i] + delivered[i]) / 2 * hours
+			e.Received[i] += (e.lastReceived[i] + received[i]) / 2 * hours
+		}
+	}
+	if ts.After(e.last) {
+		e.last, e.lastDelivered, e.lastReceived = ts, delivered, received
+	}
+	return nil
+}
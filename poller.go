@@ -0,0 +1,404 @@
+package dsmr4p1
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+)
+
+// ErrorBareLF indicates that a telegram contains a line terminated by a bare
+// LF, which a Poller with StrictCRLF set rejects.
+var ErrorBareLF = errors.New("telegram contains a line not terminated by CR+LF")
+
+// Poller reads telegrams from the P1 port. The zero value is ready to use and
+// behaves like Poll, apart from the cancellation offered by its Poll method.
+type Poller struct {
+	// ReadTimeout is the interval at which a silent input is checked for
+	// cancellation. It is only used for inputs that support read deadlines
+	// (i.e., have a SetReadDeadline method, such as many serial ports, net.Conn
+	// and a PortReader whose Port supports them). Reads from other inputs can
+	// only notice cancellation once they return. Defaults to one second.
+	ReadTimeout time.Duration
+
+	// StrictCRLF makes the Poller drop telegrams in which a line is terminated
+	// by a bare LF instead of CR+LF, as the spec requires. By default both are
+	// accepted, as some bridges and meters only emit LF. Mainly useful for
+	// conformance testing.
+	StrictCRLF bool
+
+	// Lenient relaxes the checks on the CRC trailer: lowercase hexadecimal
+	// digits (of CRC16, whether set explicitly or by default) and surrounding
+	// whitespace are accepted, as is a missing final LF at the end of the input
+	// (common for truncated captures).
+	Lenient bool
+
+	// Checksum verifies the trailer of each telegram. Defaults to CRC16, as
+	// specified by DSMR 4 and later. Use NoChecksum (or a custom Checksum) for
+	// devices that re-frame telegrams with a different or absent checksum.
+	Checksum Checksum
+
+	// ReuseBuffers makes PollEnvelopes lease the buffers of the envelopes from
+	// a pool. Call Release on each envelope once done with it, so its buffer
+	// can be reused. This eliminates the steady-state allocations of framing.
+	// Poll ignores it, as its telegrams can't be released.
+	ReuseBuffers bool
+
+	// NormalizeNumbers normalizes the numbers in the telegrams of devices that
+	// mangle them, see the NormalizeNumbers function. The Raw frame of an
+	// envelope is left as is, and its Normalized field is set when the
+	// telegram was changed.
+	NormalizeNumbers bool
+
+	// Limits, if not nil, hardens the Poller for untrusted inputs, e.g. from
+	// the network: frames are abandoned as soon as they exceed the maximum
+	// size, and frames that exceed the other limits are dropped.
+	Limits *Limits
+
+	// OnError, if not nil, is called with the reason whenever a frame is
+	// dropped (e.g. an error wrapping ErrorCRCMismatch) or reading fails,
+	// instead of logging it. It is called from the polling goroutine.
+	OnError func(err error)
+}
+
+// report reports err through OnError, or logs it.
+func (p *Poller) report(err error) {
+	if p.OnError != nil {
+		p.OnError(err)
+		return
+	}
+	log.Println(err)
+}
+
+// Poll starts polling the P1 port represented by input, just like the Poll
+// function does. Polling stops, and the returned channel is closed, when input
+// reaches EOF or ctx is cancelled.
+func (p *Poller) Poll(ctx context.Context, input io.Reader) chan Telegram {
+	ch := make(chan Telegram)
+	go func() {
+		p.run(ctx, input, false, func(e Envelope) {
+			select {
+			case ch <- e.Telegram:
+			case <-ctx.Done():
+			}
+		})
+		close(ch)
+	}()
+	return ch
+}
+
+// PollEnvelopes is like Poll, but delivers each telegram in an Envelope
+// together with its raw frame and the time it was received.
+func (p *Poller) PollEnvelopes(ctx context.Context, input io.Reader) chan Envelope {
+	ch := make(chan Envelope)
+	go func() {
+		p.run(ctx, input, p.ReuseBuffers, func(e Envelope) {
+			select {
+			case ch <- e:
+			case <-ctx.Done():
+				e.Release()
+			}
+		})
+		close(ch)
+	}()
+	return ch
+}
+
+// Starts polling and attempts to parse a telegram. Each telegram with a correct
+// CRC is passed to deliver. Returns on EOF or cancellation. If reuse is set,
+// the buffers of the envelopes are leased from bufferPool.
+//
+// Apart from the Envelope and its buffer, framing a telegram is meant to be
+// free of allocations: noise and the CRC trailer are read using ReadSlice and
+// the CRC is computed while reading the telegram and verified without
+// formatting it.
+func (p *Poller) run(ctx context.Context, input io.Reader, reuse bool, deliver func(Envelope)) {
+	if d, ok := input.(readDeadliner); ok {
+		timeout := p.ReadTimeout
+		if timeout <= 0 {
+			timeout = time.Second
+		}
+		input = &deadlineReader{ctx: ctx, rd: input, d: d, timeout: timeout}
+	}
+
+	cr := &countingReader{rd: input}
+	br := bufio.NewReader(cr)
+	var crc CRCWriter
+	// next is set when the header of the next frame was read along with the
+	// trailer of the delivered one, i.e. both arrived back-to-back.
+	next := false
+	for ctx.Err() == nil {
+		burst := next
+		next = false
+
+		// Read until we find a '/', which should be the beginning of the telegram.
+		err := skipUntil(br, '/')
+		if err == io.EOF || ctx.Err() != nil {
+			break
+		} else if err != nil {
+			p.report(err)
+			continue
+		}
+
+		// Unread the byte as the '/' is also part of the CRC computation.
+		err = br.UnreadByte()
+		if err != nil {
+			p.report(err)
+			continue
+		}
+
+		// Serial glitches can produce stray '/' characters. Only commit to a
+		// frame if the '/' is followed by something that looks like a header.
+		header, err := br.Peek(headerLen)
+		if err != nil && err != io.EOF {
+			p.report(err)
+		}
+		if !validHeader(header) {
+			br.Discard(1)
+			continue
+		}
+
+		offset := cr.n - int64(br.Buffered())
+
+		// The '!' character signals the end of the telegram.
+		var buf *[]byte
+		var data []byte
+		max := 0
+		if p.Limits != nil {
+			max = p.Limits.maxSize()
+		}
+		crc.Reset()
+		if reuse {
+			buf = bufferPool.Get().(*[]byte)
+			data, err = readUntil(br, '!', (*buf)[:0], max, &crc)
+		} else {
+			data, err = readUntil(br, '!', nil, max, &crc)
+		}
+		if err != nil {
+			p.report(err)
+			releaseBuffer(buf, data)
+			continue
+		}
+		// The four hexadecimal characters are the CRC-16 of the preceding data, delimitted by
+		// a carriage return.
+		// Note that crcBytes is only valid until the next read.
+		crcBytes, err := br.ReadSlice('\n')
+		if err != nil && !(err == io.EOF && p.Lenient) {
+			p.report(err)
+			releaseBuffer(buf, data)
+			continue
+		}
+		trailer := crcBytes
+
+		if p.StrictCRLF && (hasBareLF(data) || !bytes.HasSuffix(crcBytes, []byte("\r\n"))) {
+			p.report(ErrorBareLF)
+			releaseBuffer(buf, data)
+			continue
+		}
+		if p.Lenient {
+			crcBytes = bytes.TrimSpace(crcBytes)
+		} else {
+			crcBytes = bytes.TrimSuffix(crcBytes, []byte("\n"))
+			crcBytes = bytes.TrimSuffix(crcBytes, []byte("\r"))
+		}
+		start := 0
+		valid := false
+		if p.crc16() {
+			valid = crc.verify(crcBytes, p.Lenient)
+		} else {
+			valid = p.Checksum.Verify(data, crcBytes)
+		}
+		if !valid {
+			// If the frame started at a false start marker, the actual telegram
+			// might still be found at the end of the data.
+			if i := lastHeader(data); i > 0 && p.verify(data[i:], crcBytes) {
+				start = i
+			} else {
+				if p.crc16() {
+					p.report(fmt.Errorf("%w: %s vs %s", ErrorCRCMismatch, crcBytes, computeCRC(data)))
+				} else {
+					p.report(fmt.Errorf("%w: %s", ErrorCRCMismatch, crcBytes))
+				}
+				releaseBuffer(buf, data)
+				continue
+			}
+		}
+
+		// Keep the telegram and the raw frame in one buffer, but make sure
+		// appending to the telegram can't overwrite the trailer.
+		var raw []byte
+		if buf != nil {
+			raw = append(data, trailer...)
+			*buf = raw
+		} else {
+			raw = make([]byte, 0, len(data)+len(trailer))
+			raw = append(append(raw, data...), trailer...)
+		}
+		n := len(data) - start
+		raw = raw[start:]
+		if p.Limits != nil {
+			if err := p.Limits.Check(raw); err != nil {
+				p.report(err)
+				releaseBuffer(buf, raw)
+				continue
+			}
+		}
+		e := Envelope{
+			Telegram: Telegram(raw[:n:n]),
+			Raw:      raw,
+			Received: time.Now(),
+			Offset:   offset + int64(start),
+			Burst:    burst,
+			buf:      buf,
+		}
+		if p.NormalizeNumbers {
+			e.Telegram, e.Normalized = NormalizeNumbers(e.Telegram)
+		}
+		// Only look at what is buffered already, as reading more would make
+		// every frame look back-to-back.
+		if br.Buffered() > 0 {
+			b, _ := br.Peek(1)
+			next = b[0] == '/'
+		}
+		deliver(e)
+	}
+}
+
+// verify verifies the checksum crc of data.
+func (p *Poller) verify(data, crc []byte) bool {
+	if !p.crc16() {
+		return p.Checksum.Verify(data, crc)
+	}
+	return verifyCRC(data, crc, p.Lenient)
+}
+
+// crc16 reports whether the Poller verifies the CRC16 checksum, either by
+// default or explicitly.
+func (p *Poller) crc16() bool {
+	_, ok := p.Checksum.(crc16Checksum)
+	return p.Checksum == nil || ok
+}
+
+// readUntil reads until the first occurrence of delim, appending the data
+// (including delim) to buf and writing it to crc. If max is positive, it gives
+// up with ErrorTooLarge once more than max bytes were read.
+func readUntil(br *bufio.Reader, delim byte, buf []byte, max int, crc *CRCWriter) ([]byte, error) {
+	start := len(buf)
+	for {
+		chunk, err := br.ReadSlice(delim)
+		buf = append(buf, chunk...)
+		crc.Write(chunk)
+		if max > 0 && len(buf)-start > max {
+			return buf, ErrorTooLarge
+		}
+		if err != bufio.ErrBufferFull {
+			return buf, err
+		}
+	}
+}
+
+// skipUntil discards the input up to and including delim, without allocating.
+func skipUntil(br *bufio.Reader, delim byte) error {
+	for {
+		_, err := br.ReadSlice(delim)
+		if err != bufio.ErrBufferFull {
+			return err
+		}
+	}
+}
+
+// The header of a telegram has the form "/XXXZ", where XXX is the
+// manufacturer's flag and Z the baud rate identification.
+const headerLen = 5
+
+// validHeader reports whether b starts with a plausible telegram header.
+func validHeader(b []byte) bool {
+	if len(b) < headerLen || b[0] != '/' {
+		return false
+	}
+	for _, c := range b[1:4] {
+		if !('A' <= c && c <= 'Z' || 'a' <= c && c <= 'z') {
+			return false
+		}
+	}
+	return '0' <= b[4] && b[4] <= '9'
+}
+
+// lastHeader returns the index of the last plausible header in data, or -1 if
+// there is none.
+func lastHeader(data []byte) int {
+	for i := len(data) - headerLen; i >= 0; i-- {
+		if validHeader(data[i:]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// hasBareLF reports whether data contains a LF that is not preceded by a CR.
+func hasBareLF(data []byte) bool {
+	for i, c := range data {
+		if c == '\n' && (i == 0 || data[i-1] != '\r') {
+			return true
+		}
+	}
+	return false
+}
+
+// countingReader counts the bytes read from rd.
+type countingReader struct {
+	rd io.Reader
+	n  int64
+}
+
+func (cr *countingReader) Read(p []byte) (n int, err error) {
+	n, err = cr.rd.Read(p)
+	cr.n += int64(n)
+	return
+}
+
+// readDeadliner is implemented by readers that support read deadlines.
+type readDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// deadlineReader wraps a reader that supports read deadlines. It keeps reading
+// until data arrives or the context is cancelled, but uses the deadline to
+// check the context at least every timeout.
+type deadlineReader struct {
+	ctx     context.Context
+	rd      io.Reader
+	d       readDeadliner
+	timeout time.Duration
+}
+
+func (dr *deadlineReader) Read(p []byte) (n int, err error) {
+	for {
+		if err = dr.ctx.Err(); err != nil {
+			return 0, err
+		}
+		// Not every reader that has a SetReadDeadline method actually supports
+		// deadlines (e.g. an *os.File for a regular file). Just read in that case.
+		if dr.d.SetReadDeadline(time.Now().Add(dr.timeout)) != nil {
+			return dr.rd.Read(p)
+		}
+
+		n, err = dr.rd.Read(p)
+		if !isTimeout(err) {
+			return n, err
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// isTimeout reports whether err is caused by a read deadline.
+func isTimeout(err error) bool {
+	var t interface{ Timeout() bool }
+	return errors.As(err, &t) && t.Timeout()
+}
@@ -0,0 +1,75 @@
+package dsmr4p1
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestPollerFraming(t *testing.T) {
+	frame, payload := sampleFrame(t)
+	crc := []byte(computeCRC(payload))
+	join := func(parts ...[]byte) []byte {
+		return bytes.Join(parts, nil)
+	}
+
+	// The sample telegram with bare LF line endings.
+	lfPayload := bytes.ReplaceAll(payload, []byte("\r\n"), []byte("\n"))
+	lfFrame := join(lfPayload, []byte(computeCRC(lfPayload)), []byte("\n"))
+
+	corrupted := join(frame)
+	corrupted[bytes.Index(corrupted, []byte("123456.789"))] = '9'
+
+	tests := []struct {
+		name   string
+		poller Poller
+		input  []byte
+		want   []Telegram
+	}{
+		{"frame", Poller{}, frame, []Telegram{payload}},
+		{"frames", Poller{}, join(frame, frame), []Telegram{payload, payload}},
+		{"noise before header", Poller{}, join([]byte("\x00\xffnoise\r\n"), frame), []Telegram{payload}},
+		{"stray slash before header", Poller{}, join([]byte("/x/12\r\n/"), frame), []Telegram{payload}},
+		{"truncated frame before frame", Poller{}, join(frame[:200], frame), []Telegram{payload}},
+		{"truncated frame after frame", Poller{}, join(frame, frame[:200]), []Telegram{payload}},
+		{"CRC mismatch", Poller{}, corrupted, nil},
+		{"CRC mismatch without checksum", Poller{Checksum: NoChecksum}, corrupted, []Telegram{corrupted[:len(payload)]}},
+
+		{"LF only", Poller{}, lfFrame, []Telegram{lfPayload}},
+		{"LF only with StrictCRLF", Poller{StrictCRLF: true}, lfFrame, nil},
+		{"CR+LF with StrictCRLF", Poller{StrictCRLF: true}, frame, []Telegram{payload}},
+		{"LF trailer with StrictCRLF", Poller{StrictCRLF: true}, join(payload, crc, []byte("\n")), nil},
+
+		{"lowercase trailer", Poller{}, join(payload, bytes.ToLower(crc), []byte("\r\n")), nil},
+		{"lowercase trailer with Lenient", Poller{Lenient: true}, join(payload, bytes.ToLower(crc), []byte("\r\n")), []Telegram{payload}},
+		{"lowercase trailer with Lenient and CRC16", Poller{Lenient: true, Checksum: CRC16}, join(payload, bytes.ToLower(crc), []byte("\r\n")), []Telegram{payload}},
+		{"padded trailer", Poller{}, join(payload, []byte(" "), crc, []byte(" \r\n")), nil},
+		{"padded trailer with Lenient", Poller{Lenient: true}, join(payload, []byte(" "), crc, []byte(" \r\n")), []Telegram{payload}},
+		{"unterminated trailer", Poller{}, join(payload, crc), nil},
+		{"unterminated trailer with Lenient", Poller{Lenient: true}, join(payload, crc), []Telegram{payload}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.poller
+			p.OnError = func(err error) {}
+			var got []Telegram
+			for e := range p.PollEnvelopes(context.Background(), bytes.NewReader(tt.input)) {
+				if !bytes.HasPrefix(e.Raw, e.Telegram) {
+					t.Errorf("Raw %q does not start with Telegram %q", e.Raw, e.Telegram)
+				}
+				if want := tt.input[e.Offset : e.Offset+int64(len(e.Raw))]; !bytes.Equal(e.Raw, want) {
+					t.Errorf("Raw %q does not match the input at offset %d", e.Raw, e.Offset)
+				}
+				got = append(got, e.Telegram)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d telegrams, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if !bytes.Equal(got[i], tt.want[i]) {
+					t.Errorf("telegram %d:\ngot  %q\nwant %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package dsmr4p1
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+var errNoDeadline = errors.New("port does not support read deadlines")
+
+// ErrorPortGone indicates that a port can't be opened anymore, e.g. because a
+// FakeMeter finished its script. A PortReader whose PortOpener returns it (or
+// an error wrapping it) stops, returning io.EOF.
+var ErrorPortGone = errors.New("port is gone")
+
+// Port is a connection to the P1 port of a smartmeter. Typically this is a
+// serial port opened using a serial library of your choice (e.g.
+// github.com/tarm/serial or go.bug.st/serial), but anything that can be read
+// from and closed will do.
+type Port interface {
+	io.ReadCloser
+}
+
+// PortOpener opens a Port. Implement this interface to plug in your preferred
+// serial library (or a custom driver, for example for RS-485 converters).
+type PortOpener interface {
+	OpenPort() (Port, error)
+}
+
+// PortOpenerFunc is an adapter to allow the use of an ordinary function as a
+// PortOpener.
+type PortOpenerFunc func() (Port, error)
+
+// OpenPort calls f().
+func (f PortOpenerFunc) OpenPort() (Port, error) {
+	return f()
+}
+
+// PortReader reads from a Port and transparently reopens it when reading
+// fails, for example because the USB-to-serial cable was unplugged for a
+// moment. A partial telegram that is cut off this way simply fails its CRC
+// check, so a PortReader can be handed to Poll like any other io.Reader.
+type PortReader struct {
+	// OnEvent, if not nil, is called with a ReconnectEvent whenever reading
+	// failed and the port is about to be reopened. It is called from Read.
+	OnEvent func(e Event)
+
+	// OnError, if not nil, is called whenever opening or reading the port
+	// fails, instead of logging the error. It is called from Read.
+	OnError func(err error)
+
+	opener     PortOpener
+	retryDelay time.Duration
+
+	mu       sync.Mutex
+	port     Port
+	deadline time.Time
+	retryAt  time.Time
+	closed   bool
+	done     chan struct{}
+}
+
+// NewPortReader returns a PortReader that opens its Port using opener. When
+// opening or reading the port fails, it waits retryDelay before trying again.
+// The port is opened on the first call to Read.
+func NewPortReader(opener PortOpener, retryDelay time.Duration) *PortReader {
+	return &PortReader{opener: opener, retryDelay: retryDelay, done: make(chan struct{})}
+}
+
+// Read reads from the current port, (re)opening it when needed. It only
+// returns an error (io.EOF) after Close has been called, or once opening the
+// port fails with ErrorPortGone, and a timeout error once the read deadline
+// (see SetReadDeadline) passes, also while waiting to reopen the port.
+func (r *PortReader) Read(p []byte) (n int, err error) {
+	for {
+		if err := r.wait(); err != nil {
+			return 0, err
+		}
+		port, err := r.current()
+		if err == io.EOF || errors.Is(err, ErrorPortGone) {
+			return 0, io.EOF
+		}
+		if err != nil {
+			r.report(fmt.Errorf("error opening port: %w", err))
+			r.retryLater()
+			continue
+		}
+
+		n, err = port.Read(p)
+		if isTimeout(err) {
+			return n, err
+		}
+		if n > 0 || err == nil {
+			// Any error will surface again on the next read.
+			return n, nil
+		}
+
+		if r.release(port) {
+			return 0, io.EOF
+		}
+		r.report(fmt.Errorf("error reading from port, reopening: %w", err))
+		r.retryLater()
+		if r.OnEvent != nil {
+			r.OnEvent(ReconnectEvent{Time: time.Now(), Err: err})
+		}
+	}
+}
+
+// report reports err through OnError, or logs it.
+func (r *PortReader) report(err error) {
+	if r.OnError != nil {
+		r.OnError(err)
+		return
+	}
+	log.Println(err)
+}
+
+// Close closes the current port (if any). Blocked and subsequent calls to Read
+// return io.EOF.
+func (r *PortReader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	close(r.done)
+	if r.port == nil {
+		return nil
+	}
+	err := r.port.Close()
+	r.port = nil
+	return err
+}
+
+// SetReadDeadline sets the read deadline of the port. The deadline is also
+// applied whenever the port is reopened, and honored by Read while waiting to
+// reopen it. It returns an error if the open Port does not support read
+// deadlines.
+func (r *PortReader) SetReadDeadline(t time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deadline = t
+	if r.port == nil {
+		return nil
+	}
+	d, ok := r.port.(readDeadliner)
+	if !ok {
+		return errNoDeadline
+	}
+	return d.SetReadDeadline(t)
+}
+
+// current returns the open port, opening it if necessary.
+func (r *PortReader) current() (Port, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil, io.EOF
+	}
+	if r.port == nil {
+		port, err := r.opener.OpenPort()
+		if err != nil {
+			return nil, err
+		}
+		if d, ok := port.(readDeadliner); ok && !r.deadline.IsZero() {
+			d.SetReadDeadline(r.deadline)
+		}
+		r.port = port
+	}
+	return r.port, nil
+}
+
+// release closes port after a failed read so the next call to current reopens
+// it. It reports whether the PortReader itself has been closed.
+func (r *PortReader) release(port Port) (closed bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return true
+	}
+	if r.port == port {
+		port.Close()
+		r.port = nil
+	}
+	return false
+}
+
+// retryLater makes the next call to wait sleep for the retry delay.
+func (r *PortReader) retryLater() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.retryAt = time.Now().Add(r.retryDelay)
+}
+
+// wait sleeps until the port may be reopened. It returns io.EOF if the
+// PortReader was closed in the meantime, and os.ErrDeadlineExceeded if the
+// read deadline passes first.
+func (r *PortReader) wait() error {
+	r.mu.Lock()
+	retryAt, deadline := r.retryAt, r.deadline
+	r.mu.Unlock()
+
+	now := time.Now()
+	if !retryAt.After(now) {
+		return nil
+	}
+	until, err := retryAt, error(nil)
+	if !deadline.IsZero() && deadline.Before(retryAt) {
+		until, err = deadline, os.ErrDeadlineExceeded
+	}
+	timer := time.NewTimer(until.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-r.done:
+		return io.EOF
+	case <-timer.C:
+		return err
+	}
+}
@@ -0,0 +1,59 @@
+package dsmr4p1
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"time"
+)
+
+// ErrorParsePowerFailureLog indicates that a power failure log does not have
+// the expected "(count)(code)(end)(duration*s)..." form.
+var ErrorParsePowerFailureLog = errors.New("error parsing power failure log")
+
+// PowerFailureEvent is a long power failure, as recorded in the power failure
+// log of the meter.
+type PowerFailureEvent struct {
+	End      time.Time // The time at which the power returned.
+	Duration time.Duration
+}
+
+// maxDurationSeconds is the longest duration, in seconds, that fits in a
+// time.Duration (about 292 years).
+const maxDurationSeconds = math.MaxInt64 / float64(time.Second)
+
+// ParsePowerFailureLog parses the values of the power failure log
+// (1-0:99.97.0), e.g.:
+//
+//	1-0:99.97.0(2)(0-0:96.7.19)(101208152415W)(0000000240*s)(101208151004W)(0000000301*s)
+//
+// That is, the number of events and the OBIS code of the buffer, followed by
+// the time and duration of each event. Durations too long for a time.Duration,
+// which the format allows, are clamped to the longest one.
+func ParsePowerFailureLog(values []string) ([]PowerFailureEvent, error) {
+	if len(values) < 2 {
+		return nil, ErrorParsePowerFailureLog
+	}
+	count, err := strconv.Atoi(values[0])
+	if err != nil || count < 0 || count*2 != len(values)-2 {
+		return nil, ErrorParsePowerFailureLog
+	}
+
+	events := make([]PowerFailureEvent, count)
+	for i := range events {
+		end, err := ParseTimestamp(values[2+2*i])
+		if err != nil {
+			return nil, err
+		}
+		seconds, unit, err := ParseValueWithUnit(values[3+2*i])
+		if err != nil || unit != "s" || seconds < 0 {
+			return nil, ErrorParsePowerFailureLog
+		}
+		duration := time.Duration(math.MaxInt64)
+		if seconds < maxDurationSeconds {
+			duration = time.Duration(seconds) * time.Second
+		}
+		events[i] = PowerFailureEvent{End: end, Duration: duration}
+	}
+	return events, nil
+}
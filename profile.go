@@ -0,0 +1,244 @@
+package dsmr4p1
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrorMissingCode indicates that a telegram lacks an OBIS code its profile
+// requires.
+var ErrorMissingCode = errors.New("telegram is missing an expected OBIS code")
+
+// ErrorNoProfile indicates that a telegram matches none of the known profiles.
+var ErrorNoProfile = errors.New("telegram matches no known profile")
+
+// Profile bundles the properties of a particular version (or national flavour)
+// of the P1 specification: the serial settings, the interval at which
+// telegrams are emitted, the framing rules and the OBIS codes that every
+// telegram contains.
+type Profile struct {
+	Name string
+
+	// Serial settings of the P1 port. Parity is 'N', 'E' or 'O'.
+	BaudRate int
+	DataBits int
+	Parity   byte
+	StopBits int
+
+	// Interval at which the meter emits telegrams.
+	Interval time.Duration
+
+	// VersionCode is the OBIS code holding the version information, and Version
+	// the prefix of its value, both used for auto-detection. Both are empty if
+	// the profile is not identified by its version information.
+	VersionCode string
+	Version     string
+
+	// ExpectedCodes lists the OBIS codes every telegram should contain.
+	ExpectedCodes []string
+
+	// Framing rules and quirks, see the fields of Poller with the same names.
+	// The zero values follow the specification, with a required CRC16.
+	StrictCRLF       bool
+	Lenient          bool
+	Checksum         Checksum
+	NormalizeNumbers bool
+}
+
+// Poller returns a Poller applying the framing rules and quirks of the
+// profile.
+func (p *Profile) Poller() *Poller {
+	return &Poller{
+		StrictCRLF:       p.StrictCRLF,
+		Lenient:          p.Lenient,
+		Checksum:         p.Checksum,
+		NormalizeNumbers: p.NormalizeNumbers,
+	}
+}
+
+// The known profiles.
+var (
+	// ProfileDSMR4 is the Dutch DSMR 4.x specification.
+	ProfileDSMR4 = &Profile{
+		Name:     "DSMR4",
+		BaudRate: 115200, DataBits: 8, Parity: 'N', StopBits: 1,
+		Interval:    10 * time.Second,
+		VersionCode: ObisVersion,
+		Version:     "4",
+		ExpectedCodes: []string{
+			ObisVersion, ObisTimestamp, ObisEquipmentID,
+			ObisElectricityDeliveredTariff1, ObisElectricityDeliveredTariff2,
+			ObisElectricityReceivedTariff1, ObisElectricityReceivedTariff2,
+			ObisTariffIndicator, ObisPowerDelivered, ObisPowerReceived,
+		},
+	}
+
+	// ProfileDSMR5 is the Dutch DSMR 5.x specification. It differs from DSMR 4
+	// mostly in emitting a telegram every second.
+	ProfileDSMR5 = &Profile{
+		Name:     "DSMR5",
+		BaudRate: 115200, DataBits: 8, Parity: 'N', StopBits: 1,
+		Interval:    time.Second,
+		VersionCode: ObisVersion,
+		Version:     "5",
+		ExpectedCodes: []string{
+			ObisVersion, ObisTimestamp, ObisEquipmentID,
+			ObisElectricityDeliveredTariff1, ObisElectricityDeliveredTariff2,
+			ObisElectricityReceivedTariff1, ObisElectricityReceivedTariff2,
+			ObisTariffIndicator, ObisPowerDelivered, ObisPowerReceived, ObisVoltageL1,
+		},
+	}
+
+	// ProfileEMUCS is the Belgian e-MUCS specification, based on DSMR 5. Do
+	// note that in Belgium tariff 1 is the day tariff and tariff 2 the night
+	// tariff, the opposite of the Dutch convention.
+	ProfileEMUCS = &Profile{
+		Name:     "eMUCS",
+		BaudRate: 115200, DataBits: 8, Parity: 'N', StopBits: 1,
+		Interval:    time.Second,
+		VersionCode: ObisVersionEMUCS,
+		Version:     "5",
+		ExpectedCodes: []string{
+			ObisVersionEMUCS, ObisTimestamp, ObisEquipmentID,
+			ObisElectricityDeliveredTariff1, ObisElectricityDeliveredTariff2,
+			ObisElectricityReceivedTariff1, ObisElectricityReceivedTariff2,
+			ObisTariffIndicator, ObisPowerDelivered, ObisPowerReceived,
+		},
+	}
+
+	// ProfileESMRSE is the Swedish variant of ESMR 5, which has no version
+	// information and no separate tariff registers.
+	ProfileESMRSE = &Profile{
+		Name:     "ESMR-SE",
+		BaudRate: 115200, DataBits: 8, Parity: 'N', StopBits: 1,
+		Interval: 10 * time.Second,
+		ExpectedCodes: []string{
+			ObisTimestamp, ObisElectricityDelivered, ObisElectricityReceived,
+			ObisPowerDelivered, ObisPowerReceived,
+		},
+	}
+
+	// ProfileSmarty is the Luxembourg Smarty variant of DSMR 5, recognized by
+	// its logical device name (0-0:42.0.0). Smarty meters encrypt their
+	// telegrams; this profile applies to the decrypted telegrams, as decryption
+	// is not supported by this package.
+	ProfileSmarty = &Profile{
+		Name:     "Smarty",
+		BaudRate: 115200, DataBits: 8, Parity: 'N', StopBits: 1,
+		Interval: 10 * time.Second,
+		ExpectedCodes: []string{
+			ObisVersion, ObisTimestamp, ObisDeviceName,
+			ObisElectricityDelivered, ObisElectricityReceived, ObisPowerDelivered, ObisPowerReceived,
+		},
+	}
+)
+
+// Profiles lists the known profiles, in the order in which DetectProfile tries
+// them.
+var Profiles = []*Profile{ProfileSmarty, ProfileEMUCS, ProfileDSMR5, ProfileDSMR4, ProfileESMRSE}
+
+// LookupProfile returns the known profile with the given name (case
+// insensitive).
+func LookupProfile(name string) (*Profile, bool) {
+	for _, p := range Profiles {
+		if strings.EqualFold(p.Name, name) {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// DetectProfile returns the first known profile that matches telegram t.
+func DetectProfile(t Telegram) (*Profile, bool) {
+	r, err := t.Parse()
+	if err != nil {
+		return nil, false
+	}
+	for _, p := range Profiles {
+		if p.match(r) == nil {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// Check checks whether telegram t conforms to the profile, i.e. has the
+// expected version and OBIS codes.
+func (p *Profile) Check(t Telegram) error {
+	r, err := t.Parse()
+	if err != nil {
+		return err
+	}
+	return p.match(r)
+}
+
+func (p *Profile) match(r map[string][]string) error {
+	if p.VersionCode != "" {
+		v := r[p.VersionCode]
+		if len(v) == 0 || !strings.HasPrefix(v[0], p.Version) {
+			return errors.New("telegram does not match version of profile " + p.Name)
+		}
+	}
+	for _, code := range p.ExpectedCodes {
+		if _, ok := r[code]; !ok {
+			return fmt.Errorf("%w: %s", ErrorMissingCode, code)
+		}
+	}
+	return nil
+}
+
+// ProfileManager checks telegrams against a selected profile and, when that
+// profile repeatedly doesn't match, switches to the known profile that does.
+// This way a deployment configured with the wrong profile corrects itself. The
+// zero value starts without a profile and selects one on the first telegram.
+type ProfileManager struct {
+	// Profile is the selected profile.
+	Profile *Profile
+
+	// Failures is the number of consecutive mismatching telegrams after which
+	// another profile is tried. Defaults to 3.
+	Failures int
+
+	// OnSwitch, if not nil, is called when the manager switches from one
+	// profile to another. from is nil when the first profile is selected.
+	OnSwitch func(from, to *Profile)
+
+	failed int
+}
+
+// Add checks telegram t against the selected profile. It returns the result
+// of the check, i.e. the error is not nil if the telegram didn't match, even if
+// the manager switched to a profile that does.
+func (m *ProfileManager) Add(t Telegram) error {
+	var err error
+	if m.Profile != nil {
+		if err = m.Profile.Check(t); err == nil {
+			m.failed = 0
+			return nil
+		}
+		m.failed++
+		threshold := m.Failures
+		if threshold <= 0 {
+			threshold = 3
+		}
+		if m.failed < threshold {
+			return err
+		}
+	}
+
+	p, ok := DetectProfile(t)
+	if !ok && m.Profile == nil {
+		return ErrorNoProfile
+	}
+	if !ok || p == m.Profile {
+		return err
+	}
+	from := m.Profile
+	m.Profile, m.failed = p, 0
+	if m.OnSwitch != nil {
+		m.OnSwitch(from, p)
+	}
+	return err
+}
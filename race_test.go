@@ -0,0 +1,8 @@
+//go:build race
+// +build race
+
+package dsmr4p1
+
+// raceEnabled reports whether the race detector is enabled, which makes
+// allocation counts meaningless.
+const raceEnabled = true
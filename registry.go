@@ -0,0 +1,121 @@
+package dsmr4p1
+
+import "sync"
+
+// Description describes an OBIS code as defined in the DSMR specification.
+type Description struct {
+	// Name is the name of the field, as used in Decoded.
+	Name string
+
+	// Format is the format of the value in the notation of the specification,
+	// e.g. "F9(3,3)" for a fixed point number of up to 9 digits with 3
+	// decimals, "S4" for a string of 4 characters, "TST" for a timestamp and
+	// "Sn" for an octet string of variable length.
+	Format string
+
+	// Unit is the unit of the value as it appears in telegrams, e.g. "kWh",
+	// or empty for values without unit.
+	Unit string
+}
+
+// descriptions holds the description per OBIS code.
+var descriptions = map[string]Description{
+	ObisVersion:         {"Version", "S2", ""},
+	ObisTimestamp:       {"Timestamp", "TST", ""},
+	ObisEquipmentID:     {"EquipmentID", "Sn", ""},
+	ObisTextMessageCode: {"TextMessageCode", "Sn", ""},
+	ObisTextMessage:     {"TextMessage", "Sn", ""},
+
+	ObisElectricityDeliveredTariff1: {"ElectricityDeliveredTariff1", "F9(3,3)", "kWh"},
+	ObisElectricityDeliveredTariff2: {"ElectricityDeliveredTariff2", "F9(3,3)", "kWh"},
+	ObisElectricityReceivedTariff1:  {"ElectricityReceivedTariff1", "F9(3,3)", "kWh"},
+	ObisElectricityReceivedTariff2:  {"ElectricityReceivedTariff2", "F9(3,3)", "kWh"},
+	ObisTariffIndicator:             {"TariffIndicator", "S4", ""},
+	ObisPowerDelivered:              {"PowerDelivered", "F5(3,3)", "kW"},
+	ObisPowerReceived:               {"PowerReceived", "F5(3,3)", "kW"},
+	ObisPowerThreshold:              {"PowerThreshold", "F4(1,1)", "kW"},
+	ObisSwitchPosition:              {"SwitchPosition", "I1", ""},
+
+	ObisVoltageL1:        {"VoltageL1", "F4(1,1)", "V"},
+	ObisVoltageL2:        {"VoltageL2", "F4(1,1)", "V"},
+	ObisVoltageL3:        {"VoltageL3", "F4(1,1)", "V"},
+	ObisCurrentL1:        {"CurrentL1", "F3(0,0)", "A"},
+	ObisCurrentL2:        {"CurrentL2", "F3(0,0)", "A"},
+	ObisCurrentL3:        {"CurrentL3", "F3(0,0)", "A"},
+	ObisPowerDeliveredL1: {"PowerDeliveredL1", "F5(3,3)", "kW"},
+	ObisPowerDeliveredL2: {"PowerDeliveredL2", "F5(3,3)", "kW"},
+	ObisPowerDeliveredL3: {"PowerDeliveredL3", "F5(3,3)", "kW"},
+	ObisPowerReceivedL1:  {"PowerReceivedL1", "F5(3,3)", "kW"},
+	ObisPowerReceivedL2:  {"PowerReceivedL2", "F5(3,3)", "kW"},
+	ObisPowerReceivedL3:  {"PowerReceivedL3", "F5(3,3)", "kW"},
+
+	ObisPowerFailures:     {"PowerFailures", "F5(0,0)", ""},
+	ObisLongPowerFailures: {"LongPowerFailures", "F5(0,0)", ""},
+	ObisPowerFailureLog:   {"PowerFailureLog", "Buffer of TST, F10(0,0)", "s"},
+	ObisVoltageSagsL1:     {"VoltageSagsL1", "F5(0,0)", ""},
+	ObisVoltageSagsL2:     {"VoltageSagsL2", "F5(0,0)", ""},
+	ObisVoltageSagsL3:     {"VoltageSagsL3", "F5(0,0)", ""},
+	ObisVoltageSwellsL1:   {"VoltageSwellsL1", "F5(0,0)", ""},
+	ObisVoltageSwellsL2:   {"VoltageSwellsL2", "F5(0,0)", ""},
+	ObisVoltageSwellsL3:   {"VoltageSwellsL3", "F5(0,0)", ""},
+
+	ObisMBusDeviceType:    {"MBusDeviceType", "F3(0,0)", ""},
+	ObisMBusEquipmentID:   {"MBusEquipmentID", "Sn", ""},
+	ObisGasDelivered:      {"GasDelivered", "TST, F8(2,3)", "m3"},
+	ObisMBusValvePosition: {"MBusValvePosition", "I1", ""},
+}
+
+// Describe returns the description of an OBIS code, e.g. to label values in a
+// dashboard. The M-Bus codes are described on any channel. The boolean is
+// false for unknown codes.
+func Describe(code string) (Description, bool) {
+	if d, ok := descriptions[code]; ok {
+		return d, true
+	}
+	c, err := ParseObisCode(code)
+	if err != nil || c.Medium != 0 || c.Channel < 1 || c.Channel > 4 {
+		return Description{}, false
+	}
+	d, ok := descriptions[c.WithChannel(1).String()]
+	return d, ok
+}
+
+// ValueParser parses the values of an OBIS code, i.e. the contents of its
+// brackets, into a typed value.
+type ValueParser func(values []string) (interface{}, error)
+
+var (
+	parsersMu sync.RWMutex
+	parsers   = make(map[string]ValueParser)
+)
+
+// Register registers the parser for an OBIS code that Decode doesn't know,
+// e.g. a vendor-specific code. Decode then stores the parsed value in
+// Decoded.Extra. Register is typically called from an init function. It panics
+// if parser is nil, or if the code is decoded by Decode or already registered.
+//
+//	dsmr4p1.Register("0-0:96.1.4", func(values []string) (interface{}, error) {
+//		return strconv.Atoi(values[0])
+//	})
+func Register(code string, parser ValueParser) {
+	parsersMu.Lock()
+	defer parsersMu.Unlock()
+	if parser == nil {
+		panic("dsmr4p1: Register parser is nil")
+	}
+	if _, dup := decoders[code]; dup || isMBusCode(code) {
+		panic("dsmr4p1: Register called for decoded code " + code)
+	}
+	if _, dup := parsers[code]; dup {
+		panic("dsmr4p1: Register called twice for code " + code)
+	}
+	parsers[code] = parser
+}
+
+// lookupParser returns the registered parser of an OBIS code, if any.
+func lookupParser(code string) (ValueParser, bool) {
+	parsersMu.RLock()
+	defer parsersMu.RUnlock()
+	p, ok := parsers[code]
+	return p, ok
+}
@@ -0,0 +1,79 @@
+package dsmr4p1
+
+import "time"
+
+// Ring is a ring buffer retaining the most recent telegrams, for example to
+// show the last hour of data right away without a database. It is not safe for
+// concurrent use; use it through a Hub for that.
+type Ring struct {
+	// MaxAge, if positive, additionally drops the telegrams that were received
+	// longer than MaxAge before the most recently added one.
+	MaxAge time.Duration
+
+	entries []ringEntry
+	start   int
+	count   int
+}
+
+type ringEntry struct {
+	telegram Telegram
+	received time.Time
+}
+
+// NewRing returns a Ring that retains (at most) the last size telegrams.
+func NewRing(size int) *Ring {
+	return &Ring{entries: make([]ringEntry, size)}
+}
+
+// Add adds telegram t, received at the given time, dropping the oldest
+// telegram(s) if necessary.
+func (r *Ring) Add(t Telegram, received time.Time) {
+	if len(r.entries) == 0 {
+		return
+	}
+	r.entries[(r.start+r.count)%len(r.entries)] = ringEntry{telegram: t, received: received}
+	if r.count == len(r.entries) {
+		r.start = (r.start + 1) % len(r.entries)
+	} else {
+		r.count++
+	}
+
+	if r.MaxAge > 0 {
+		oldest := received.Add(-r.MaxAge)
+		for r.count > 0 && r.entries[r.start].received.Before(oldest) {
+			r.entries[r.start] = ringEntry{}
+			r.start = (r.start + 1) % len(r.entries)
+			r.count--
+		}
+	}
+}
+
+// Len returns the number of telegrams in the ring.
+func (r *Ring) Len() int {
+	return r.count
+}
+
+// Last returns (at most) the last n telegrams, oldest first. It returns no
+// telegrams for a negative n.
+func (r *Ring) Last(n int) []Telegram {
+	if n > r.count {
+		n = r.count
+	}
+	if n < 0 {
+		n = 0
+	}
+	result := make([]Telegram, 0, n)
+	for i := r.count - n; i < r.count; i++ {
+		result = append(result, r.entries[(r.start+i)%len(r.entries)].telegram)
+	}
+	return result
+}
+
+// Since returns the telegrams received at or after t, oldest first.
+func (r *Ring) Since(t time.Time) []Telegram {
+	n := 0
+	for n < r.count && !r.entries[(r.start+r.count-1-n)%len(r.entries)].received.Before(t) {
+		n++
+	}
+	return r.Last(n)
+}
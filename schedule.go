@@ -0,0 +1,87 @@
+package dsmr4p1
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// TariffSwitch is a recurring switch of the tariff indicator (0-0:96.14.0).
+type TariffSwitch struct {
+	Weekday time.Weekday
+	Time    time.Duration // Since midnight, rounded to 15 minutes.
+	Tariff  int           // The tariff switched to.
+	Count   int           // The number of times the switch was observed.
+}
+
+// TariffSchedule infers the tariff switching schedule of a meter from the
+// transitions of its tariff indicator, so e.g. a cost calculation doesn't need
+// the switch hours to be entered manually. Feed it telegrams using Add. The
+// zero value is ready to use.
+type TariffSchedule struct {
+	// MinCount is the number of times a switch has to be observed to be part
+	// of the schedule, which filters out e.g. public holidays. Defaults to 2.
+	MinCount int
+
+	tariff   int
+	switches map[TariffSwitch]int
+}
+
+// Add processes telegram t.
+func (s *TariffSchedule) Add(t Telegram) error {
+	r, ts, err := t.parseTimestamped()
+	if err != nil {
+		return err
+	}
+	v := r[ObisTariffIndicator]
+	if len(v) == 0 {
+		return nil
+	}
+	tariff, err := strconv.Atoi(v[0])
+	if err != nil {
+		return err
+	}
+	if s.tariff != 0 && tariff != s.tariff {
+		if s.switches == nil {
+			s.switches = make(map[TariffSwitch]int)
+		}
+		// The wall clock time, which is also correct on the days DST starts or
+		// ends.
+		clock := time.Duration(ts.Hour())*time.Hour + time.Duration(ts.Minute())*time.Minute
+		key := TariffSwitch{
+			Weekday: ts.Weekday(),
+			Time:    clock.Round(15 * time.Minute),
+			Tariff:  tariff,
+		}
+		// A switch just before midnight rounds to midnight of the next day.
+		if key.Time == 24*time.Hour {
+			key.Weekday, key.Time = (key.Weekday+1)%7, 0
+		}
+		s.switches[key]++
+	}
+	s.tariff = tariff
+	return nil
+}
+
+// Schedule returns the inferred schedule, ordered by weekday (starting on
+// Sunday) and time.
+func (s *TariffSchedule) Schedule() []TariffSwitch {
+	minCount := s.MinCount
+	if minCount <= 0 {
+		minCount = 2
+	}
+	var schedule []TariffSwitch
+	for key, n := range s.switches {
+		if n >= minCount {
+			key.Count = n
+			schedule = append(schedule, key)
+		}
+	}
+	sort.Slice(schedule, func(i, j int) bool {
+		if schedule[i].Weekday != schedule[j].Weekday {
+			return schedule[i].Weekday < schedule[j].Weekday
+		}
+		return schedule[i].Time < schedule[j].Time
+	})
+	return schedule
+}
@@ -0,0 +1,110 @@
+package dsmr4p1
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// Splitter splits a large raw capture of telegrams into parts, e.g. per day,
+// validating the CRCs along the way. Set either PerDay or PerTelegrams, and
+// Create.
+type Splitter struct {
+	// PerDay starts a new part for every day, based on the telegram timestamps
+	// (Dutch time). The key of a part is its date, formatted as "2006-01-02".
+	PerDay bool
+
+	// PerTelegrams starts a new part after the given number of telegrams. The
+	// key of a part is its sequence number, formatted as "%06d".
+	PerTelegrams int
+
+	// Create creates the writer for the part identified by key. It is closed
+	// once the part is complete.
+	Create func(key string) (io.WriteCloser, error)
+
+	// Poller is used to read the telegrams from the capture, which allows for
+	// e.g. lenient framing. Defaults to the zero Poller.
+	Poller *Poller
+}
+
+// CorruptRegion is a region of a capture that doesn't hold a valid telegram,
+// e.g. because of a CRC error or noise on the line.
+type CorruptRegion struct {
+	Offset int64 // In bytes, from the start of the capture.
+	Length int64
+}
+
+// SplitReport reports the results of Split.
+type SplitReport struct {
+	Telegrams int
+	Parts     int
+	Corrupt   []CorruptRegion
+}
+
+// Split reads the capture from r and writes its valid telegrams, byte for
+// byte, to the parts.
+func (s *Splitter) Split(r io.Reader) (SplitReport, error) {
+	var report SplitReport
+	if !s.PerDay && s.PerTelegrams <= 0 {
+		return report, fmt.Errorf("splitter needs either PerDay or PerTelegrams")
+	}
+	poller := s.Poller
+	if poller == nil {
+		poller = new(Poller)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cr := &countingReader{rd: r}
+
+	var part io.WriteCloser
+	var key string
+	var inPart int
+	var end int64
+	var err error
+	for e := range poller.PollEnvelopes(ctx, cr) {
+		if e.Offset > end {
+			report.Corrupt = append(report.Corrupt, CorruptRegion{Offset: end, Length: e.Offset - end})
+		}
+		end = e.Offset + int64(len(e.Raw))
+
+		newKey := key
+		if s.PerDay {
+			_, t, err := e.Telegram.parseTimestamped()
+			if err != nil {
+				// Without a timestamp the telegram can't be assigned to a day.
+				report.Corrupt = append(report.Corrupt, CorruptRegion{Offset: e.Offset, Length: int64(len(e.Raw))})
+				continue
+			}
+			newKey = t.Format("2006-01-02")
+		} else if part == nil || inPart == s.PerTelegrams {
+			newKey = fmt.Sprintf("%06d", report.Parts+1)
+		}
+
+		if part == nil || newKey != key {
+			if part != nil {
+				if err = part.Close(); err != nil {
+					return report, err
+				}
+			}
+			if part, err = s.Create(newKey); err != nil {
+				return report, err
+			}
+			key, inPart = newKey, 0
+			report.Parts++
+		}
+		if _, err = part.Write(e.Raw); err != nil {
+			part.Close()
+			return report, err
+		}
+		inPart++
+		report.Telegrams++
+	}
+	if cr.n > end {
+		report.Corrupt = append(report.Corrupt, CorruptRegion{Offset: end, Length: cr.n - end})
+	}
+	if part != nil {
+		err = part.Close()
+	}
+	return report, err
+}
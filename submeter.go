@@ -0,0 +1,45 @@
+package dsmr4p1
+
+import (
+	"fmt"
+	"time"
+)
+
+// SubMeter is an electricity meter connected to an M-Bus channel of the main
+// meter (M-Bus device type 2), e.g. to meter a heat pump or charging point
+// separately. Its registers are not included in the registers of the main
+// meter.
+type SubMeter struct {
+	Channel     int    // M-Bus channel, 1-4.
+	EquipmentID string // Decoded from 0-n:96.1.0, empty if not reported.
+
+	// Time is the time at which the sub-meter captured the reading, which may
+	// lag the time of the telegram.
+	Time   time.Time
+	Energy float64 // In Wh.
+}
+
+// SubMeters returns the electricity sub-meters in telegram t, ordered by
+// channel.
+func SubMeters(t Telegram) ([]SubMeter, error) {
+	devices, err := MBusDevices(t)
+	if err != nil {
+		return nil, err
+	}
+	var meters []SubMeter
+	for _, d := range devices {
+		if d.Type != DeviceElectricity {
+			continue
+		}
+		if !d.Time.IsZero() && d.Reading.Unit != UnitWh {
+			return nil, fmt.Errorf("%w: 0-%d:24.2.1 has %q instead of %q", ErrorUnexpectedUnit, d.Channel, d.Reading.Unit, UnitWh)
+		}
+		meters = append(meters, SubMeter{
+			Channel:     d.Channel,
+			EquipmentID: d.EquipmentID,
+			Time:        d.Time,
+			Energy:      d.Reading.Value,
+		})
+	}
+	return meters, nil
+}
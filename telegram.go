@@ -5,58 +5,353 @@ import (
 	"errors"
 	"strconv"
 	"strings"
+	"time"
 )
 
-// Telegram holds the a P1 telegram. It is essentially a slice of bytes.
+var (
+	// ErrorInvalidHeader indicates that a telegram does not start with a
+	// "/XXXZ" header.
+	ErrorInvalidHeader = errors.New("telegram does not start with a valid header")
+	// ErrorMissingEnd indicates that the '!' marking the end of a telegram is
+	// missing.
+	ErrorMissingEnd = errors.New("telegram is not terminated by '!'")
+	// ErrorCRCMismatch indicates that the CRC in a telegram's trailer does not
+	// match its contents.
+	ErrorCRCMismatch = errors.New("CRC value does not match telegram")
+	// ErrorMissingTimestamp indicates that a telegram lacks the timestamp
+	// (0-0:1.0.0).
+	ErrorMissingTimestamp = errors.New("telegram does not contain a timestamp")
+)
+
+// Telegram holds the a P1 telegram. It is essentially a slice of bytes. The
+// telegrams delivered by Poll end with the '!', but a Telegram may also
+// include the CRC trailer (see WithUpdatedCRC).
 type Telegram []byte
 
-// Identifier returns the identifier in the telegram.
-func (t Telegram) Identifier() string {
+// Identification holds the information in the header of a telegram, which has
+// the form "/XXXZ Ident".
+type Identification struct {
+	// Vendor is the three letter flag of the manufacturer (XXX).
+	Vendor string
+	// Baud is the baud rate identification digit (Z). Its meaning differs per
+	// version of the specification, e.g. '5' for 115200 baud in DSMR 4 and up.
+	Baud byte
+	// Ident is the free-form identification of the meter.
+	Ident string
+}
+
+// Identifier returns the identification in the header of the telegram. It
+// returns ErrorInvalidHeader if the header is malformed or not terminated by a
+// line ending.
+func (t Telegram) Identifier() (Identification, error) {
 	// According to the documentation, the telegram starts with:
-	// "/XXXZ Ident CR LF CR LF", followed by the data.
-	i := bytes.Index(t, []byte("\r\n\r\n"))
-	return string(t[5:i])
+	// "/XXXZ Ident CR LF CR LF", followed by the data. Some meters omit the CRs.
+	if !validHeader(t) {
+		return Identification{}, ErrorInvalidHeader
+	}
+	i := bytes.IndexByte(t, '\n')
+	if i == -1 {
+		return Identification{}, ErrorInvalidHeader
+	}
+	return Identification{
+		Vendor: string(t[1:4]),
+		Baud:   t[4],
+		Ident:  string(bytes.TrimSuffix(t[headerLen:i], []byte("\r"))),
+	}, nil
 }
 
 // Parse attempts to parse the telegram. It returns a map of strings to string
 // slices. The keys in the map are the ID-codes, the strings in the slice are
 // are the value between brackets for that ID-code.
+//
+// Parse converts the telegram to a string once, which the keys and values
+// share, and all value slices share a single backing array. Apart from those
+// two allocations it only allocates the map.
 func (t Telegram) Parse() (map[string][]string, error) {
 	// Parse the telegram in a relatively naive way. Of course this
 	// is not properly langsec approved :)
 
-	lines := strings.Split(string(t), "\r\n")
+	s := string(t.payload())
 
-	if len(lines) < 2 {
+	header, s, ok := cutLine(s)
+	if !ok {
+		return nil, errors.New("parse error: unexpected number of lines in telegram")
+	}
+	separator, s, ok := cutLine(s)
+	if !ok {
 		return nil, errors.New("parse error: unexpected number of lines in telegram")
 	}
 
 	// Some additional checks
-	if lines[0][0] != '/' {
+	if len(header) == 0 || header[0] != '/' {
 		return nil, errors.New("expected '/' missing in first line of telegram")
 	}
-	if len(lines[1]) != 0 {
+	if len(separator) != 0 {
 		return nil, errors.New("missing separating new line (CR+LF) between identifier and data in telegram")
 	}
 
-	result := make(map[string][]string)
-	// Iterate over the lines and try to parse the data. The first two lines can
-	// be skipped because they should contain the identifier (see Identifier())
-	// and a new-line.  The last line is skipped because it should only contain an
-	// exclamation mark.
-	for i, l := range lines[2 : len(lines)-1] {
-		idCodeEnd := strings.Index(l, "(")
+	result := make(map[string][]string, strings.Count(s, "\n"))
+	// Every value starts with a '(', so this is enough for all values.
+	values := make([]string, 0, strings.Count(s, "("))
+	// Iterate over the lines and try to parse the data. The first two lines are
+	// skipped above because they should contain the identifier (see
+	// Identifier()) and a new-line. The last line is skipped because it should
+	// only contain an exclamation mark (and has no line ending).
+	var idCode string
+	var start int
+	for i := 0; ; i++ {
+		var l string
+		l, s, ok = cutLine(s)
+		if !ok {
+			break
+		}
+		idCodeEnd := strings.IndexByte(l, '(')
 		if idCodeEnd == -1 {
 			return nil, errors.New("Expected '(', not found on line" + strconv.Itoa(i))
 		}
 
-		idCode := l[:idCodeEnd]
-
-		// The rest of the line is a number of values in round brackets "()".
-		// Let's use a simple split on ")(" to get those.
-		parts := strings.Split(l[idCodeEnd+1:len(l)-1], ")(")
-		result[idCode] = parts
+		// Older meters (DSMR 2.2) continue a record on the next line, e.g. the
+		// value of the gas reading. Such a line consists of values only, which
+		// are appended to those of the previous line.
+		if idCodeEnd > 0 || i == 0 {
+			idCode = l[:idCodeEnd]
+			start = len(values)
+		}
+		values = appendValues(values, l[idCodeEnd:])
+		result[idCode] = values[start:len(values):len(values)]
 	}
 
 	return result, nil
 }
+
+// cutLine returns the first line of s and the rest of s. Lines are normally
+// terminated by CR+LF, but a bare LF is accepted as well. If s contains no line
+// ending, ok is false.
+func cutLine(s string) (line, rest string, ok bool) {
+	i := strings.IndexByte(s, '\n')
+	if i == -1 {
+		return s, "", false
+	}
+	return strings.TrimSuffix(s[:i], "\r"), s[i+1:], true
+}
+
+// appendValues appends the values in the brackets of l, which starts with the
+// first '(' of a line, to values.
+func appendValues(values []string, l string) []string {
+	// The rest of the line is a number of values in round brackets "()".
+	// Let's use a simple split on ")(" to get those.
+	var rest string
+	if len(l) >= 2 {
+		rest = l[1 : len(l)-1]
+	}
+	for {
+		j := strings.Index(rest, ")(")
+		if j == -1 {
+			return append(values, rest)
+		}
+		values = append(values, rest[:j])
+		rest = rest[j+2:]
+	}
+}
+
+// findLine returns the line of the given OBIS code, from its first '(' up to
+// the line ending, and the rest of the telegram following that line. If the
+// code occurs more than once, the last line is returned, like Parse keeps the
+// values of the last one. The boolean is false if the telegram has no such
+// line.
+func (t Telegram) findLine(code string) (line, rest Telegram, ok bool) {
+	p := t.payload()
+	for n := len(p); n > 0; {
+		i := bytes.LastIndex(p[:n], []byte(code))
+		if i == -1 {
+			return nil, nil, false
+		}
+		end := i + len(code)
+		if i > 0 && p[i-1] == '\n' && end < len(p) && p[end] == '(' {
+			line, rest = cutLineBytes(p[end:])
+			return line, rest, true
+		}
+		n = i
+	}
+	return nil, nil, false
+}
+
+// cutLineBytes returns the first line of t, without line ending, and the rest
+// of t.
+func cutLineBytes(t Telegram) (line, rest Telegram) {
+	if i := bytes.IndexByte(t, '\n'); i != -1 {
+		line, rest = t[:i], t[i+1:]
+	} else {
+		line = t
+	}
+	return bytes.TrimSuffix(line, []byte("\r")), rest
+}
+
+// Get returns the values of an OBIS code, like the map returned by Parse does,
+// but without parsing the rest of the telegram. This makes it the cheaper
+// choice for looking up a few codes; use an Index for many lookups.
+func (t Telegram) Get(code string) ([]string, bool) {
+	line, rest, ok := t.findLine(code)
+	if !ok {
+		return nil, false
+	}
+	values := appendValues(nil, string(line))
+	// Append the values on continuation lines (see Parse).
+	for len(rest) > 0 && rest[0] == '(' {
+		line, rest = cutLineBytes(rest)
+		values = appendValues(values, string(line))
+	}
+	return values, true
+}
+
+// Has reports whether the telegram contains the OBIS code.
+func (t Telegram) Has(code string) bool {
+	_, _, ok := t.findLine(code)
+	return ok
+}
+
+// Index looks up OBIS codes in a telegram, like Telegram.Get and Has do, but
+// parses the telegram once, on the first lookup, and answers the following
+// lookups from the result. Create it using NewIndex; it is not safe for
+// concurrent use.
+type Index struct {
+	t      Telegram
+	values map[string][]string
+	err    error
+}
+
+// NewIndex returns an Index of telegram t.
+func NewIndex(t Telegram) *Index {
+	return &Index{t: t}
+}
+
+// parse parses the telegram, if not done before.
+func (x *Index) parse() {
+	if x.values == nil && x.err == nil {
+		x.values, x.err = x.t.Parse()
+	}
+}
+
+// Get returns the values of an OBIS code. The boolean is false if the telegram
+// doesn't contain the code, or can't be parsed (see Err).
+func (x *Index) Get(code string) ([]string, bool) {
+	x.parse()
+	v, ok := x.values[code]
+	return v, ok
+}
+
+// Has reports whether the telegram contains the OBIS code.
+func (x *Index) Has(code string) bool {
+	_, ok := x.Get(code)
+	return ok
+}
+
+// Err returns the error parsing the telegram, if any.
+func (x *Index) Err() error {
+	x.parse()
+	return x.err
+}
+
+// Timestamp returns the timestamp (0-0:1.0.0) of the telegram. It only looks
+// for that line, so it is much cheaper than parsing the telegram. It returns
+// ErrorMissingTimestamp if the telegram has no timestamp.
+func (t Telegram) Timestamp() (time.Time, error) {
+	line, _, ok := t.findLine(ObisTimestamp)
+	if !ok {
+		return time.Time{}, ErrorMissingTimestamp
+	}
+	i := bytes.IndexByte(line, ')')
+	if i == -1 {
+		return time.Time{}, ErrorMissingTimestamp
+	}
+	return ParseTimestampBytes(line[1:i])
+}
+
+// parseTimestamped parses the telegram and its timestamp.
+func (t Telegram) parseTimestamped() (map[string][]string, time.Time, error) {
+	r, err := t.Parse()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if len(r[ObisTimestamp]) == 0 {
+		return nil, time.Time{}, ErrorMissingTimestamp
+	}
+	ts, err := ParseTimestamp(r[ObisTimestamp][0])
+	return r, ts, err
+}
+
+// Validate checks the structure of the telegram. If the telegram includes a
+// CRC trailer, the CRC is verified as well.
+func (t Telegram) Validate() error {
+	if !validHeader(t) {
+		return ErrorInvalidHeader
+	}
+	i := bytes.LastIndexByte(t, '!')
+	if i == -1 {
+		return ErrorMissingEnd
+	}
+	if _, err := t.Parse(); err != nil {
+		return err
+	}
+	trailer := bytes.TrimSpace(t[i+1:])
+	if len(trailer) > 0 && string(trailer) != computeCRC(t[:i+1]) {
+		return ErrorCRCMismatch
+	}
+	return nil
+}
+
+// WithUpdatedCRC returns a copy of the telegram with a freshly computed CRC
+// trailer (replacing the existing one, if any). Use it after modifying a
+// telegram to turn it back into a frame that a meter could have sent.
+func (t Telegram) WithUpdatedCRC() Telegram {
+	p := t.payload()
+	crc := computeCRC(p)
+	u := make(Telegram, 0, len(p)+len(crc)+2)
+	u = append(u, p...)
+	u = append(u, crc...)
+	return append(u, "\r\n"...)
+}
+
+// MarshalText implements encoding.TextMarshaler. It returns the telegram with
+// its CRC trailer, which is computed if the telegram has none, so the telegram
+// can be stored in text-based formats such as JSON and restored verifiably. An
+// empty telegram yields empty text, and a telegram that is not a valid frame
+// (see Validate) an error.
+func (t Telegram) MarshalText() ([]byte, error) {
+	if len(t) == 0 {
+		return []byte{}, nil
+	}
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	if len(t.payload()) == len(t) {
+		return t.WithUpdatedCRC(), nil
+	}
+	return append([]byte(nil), t...), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. It validates the telegram
+// (see Validate), including its CRC if present, and stores it as is, trailer
+// included, so marshaling it again yields the same text. Empty text yields a
+// nil telegram.
+func (t *Telegram) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*t = nil
+		return nil
+	}
+	u := Telegram(text)
+	if err := u.Validate(); err != nil {
+		return err
+	}
+	*t = append(Telegram(nil), text...)
+	return nil
+}
+
+// payload returns the telegram without its CRC trailer (if any).
+func (t Telegram) payload() Telegram {
+	i := bytes.LastIndexByte(t, '!')
+	if i == -1 {
+		return t
+	}
+	return t[:i+1]
+}
@@ -0,0 +1,117 @@
+package dsmr4p1
+
+import (
+	"time"
+)
+
+// The registers with the electricity delivered to the client, per tariff.
+var deliveredTariffCodes = map[int]string{
+	1: ObisElectricityDeliveredTariff1,
+	2: ObisElectricityDeliveredTariff2,
+}
+
+// TimeOfUse analyzes how electricity consumption is distributed over the
+// tariffs, the hours of the day and the days of the week. This helps to
+// evaluate whether e.g. a dynamic contract would pay off. Feed it telegrams
+// using Add. The zero value is ready to use and keeps all consumption since
+// the first telegram.
+type TimeOfUse struct {
+	// Window limits the statistics to the consumption in the given period
+	// before the most recent telegram. Zero means no limit.
+	Window time.Duration
+
+	last    map[int]float64
+	latest  time.Time
+	buckets []touBucket
+}
+
+// Consumption is attributed to buckets per hour and tariff, which keeps the
+// memory use limited even at one telegram per second.
+type touBucket struct {
+	hour   time.Time
+	tariff int
+	energy float64
+}
+
+// TimeOfUseStats holds the distribution of consumption, in Wh.
+type TimeOfUseStats struct {
+	Total     float64
+	ByTariff  map[int]float64
+	ByHour    [24]float64
+	ByWeekday [7]float64 // Indexed by time.Weekday
+}
+
+// Add adds the consumption since the previous telegram. The first telegram only
+// serves as a baseline.
+func (u *TimeOfUse) Add(t Telegram) error {
+	r, ts, err := t.parseTimestamped()
+	if err != nil {
+		return err
+	}
+	if !ts.After(u.latest) {
+		// Out of order or duplicate telegram.
+		return nil
+	}
+	u.latest = ts
+
+	if u.last == nil {
+		u.last = make(map[int]float64)
+	}
+	hour := ts.Truncate(time.Hour)
+	for tariff, code := range deliveredTariffCodes {
+		if len(r[code]) == 0 {
+			continue
+		}
+		value, _, err := ParseValueWithUnit(r[code][0])
+		if err != nil {
+			return err
+		}
+		last, ok := u.last[tariff]
+		u.last[tariff] = value
+		// A decreasing register means the meter was replaced or reset, so only
+		// use it as the new baseline.
+		if !ok || value <= last {
+			continue
+		}
+		u.add(hour, tariff, value-last)
+	}
+	u.prune()
+	return nil
+}
+
+// add attributes energy to the bucket for hour and tariff.
+func (u *TimeOfUse) add(hour time.Time, tariff int, energy float64) {
+	for i := len(u.buckets) - 1; i >= 0 && u.buckets[i].hour.Equal(hour); i-- {
+		if u.buckets[i].tariff == tariff {
+			u.buckets[i].energy += energy
+			return
+		}
+	}
+	u.buckets = append(u.buckets, touBucket{hour: hour, tariff: tariff, energy: energy})
+}
+
+// prune drops the buckets that fell out of the window.
+func (u *TimeOfUse) prune() {
+	if u.Window <= 0 {
+		return
+	}
+	start := u.latest.Add(-u.Window)
+	i := 0
+	for i < len(u.buckets) && u.buckets[i].hour.Add(time.Hour).Before(start) {
+		i++
+	}
+	u.buckets = u.buckets[i:]
+}
+
+// Stats returns the distribution of the consumption seen so far (within the
+// window). Hours and weekdays are in Dutch time.
+func (u *TimeOfUse) Stats() TimeOfUseStats {
+	s := TimeOfUseStats{ByTariff: make(map[int]float64)}
+	for _, b := range u.buckets {
+		s.Total += b.energy
+		s.ByTariff[b.tariff] += b.energy
+		s.ByHour[b.hour.Hour()] += b.energy
+		s.ByWeekday[b.hour.Weekday()] += b.energy
+	}
+	return s
+}
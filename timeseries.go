@@ -0,0 +1,153 @@
+package dsmr4p1
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrorInvalidStep indicates that the step of an aggregation is not positive.
+var ErrorInvalidStep = errors.New("aggregation step must be positive")
+
+// Point holds the values of selected OBIS codes at a point in time.
+type Point struct {
+	Time   time.Time
+	Values map[string]float64
+}
+
+// HistoryStore stores the values of telegrams for later queries. It allows
+// e.g. reports and dashboards to serve history from whichever backend is used.
+// TimeSeries is an in-memory implementation; other implementations typically
+// store the points in a database.
+type HistoryStore interface {
+	// Append stores the values of telegram t.
+	Append(t Telegram) error
+
+	// Query returns the points from (inclusive) up to to (exclusive), sorted by
+	// time.
+	Query(from, to time.Time) ([]Point, error)
+
+	// Aggregate returns the points from (inclusive) up to to (exclusive),
+	// averaged per interval of length step, like TimeSeries.Downsample. It
+	// returns ErrorInvalidStep if step is not positive.
+	Aggregate(from, to time.Time, step time.Duration) ([]Point, error)
+}
+
+var _ HistoryStore = (*TimeSeries)(nil)
+
+// TimeSeries is a small in-memory time series of selected numeric values from
+// telegrams, indexed by the telegram timestamps. It supports range queries and
+// downsampling, for example to draw charts of the last 24 hours without
+// external storage. It is safe for concurrent use.
+type TimeSeries struct {
+	// Codes lists the OBIS codes whose values are recorded. Values are in base
+	// units (see ParseValueWithUnit).
+	Codes []string
+
+	// MaxAge, if positive, drops the points older than MaxAge before the most
+	// recent point.
+	MaxAge time.Duration
+
+	mu     sync.RWMutex
+	points []Point // Sorted by time.
+}
+
+// Add adds the values in telegram t.
+func (s *TimeSeries) Add(t Telegram) error {
+	r, ts, err := t.parseTimestamped()
+	if err != nil {
+		return err
+	}
+	p := Point{Time: ts, Values: make(map[string]float64, len(s.Codes))}
+	for _, code := range s.Codes {
+		v, _, ok := numericValue(r[code])
+		if ok {
+			p.Values[code] = v
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := sort.Search(len(s.points), func(i int) bool { return s.points[i].Time.After(ts) })
+	if i == len(s.points) {
+		s.points = append(s.points, p)
+	} else {
+		s.points = append(s.points, Point{})
+		copy(s.points[i+1:], s.points[i:])
+		s.points[i] = p
+	}
+
+	if s.MaxAge > 0 {
+		oldest := s.points[len(s.points)-1].Time.Add(-s.MaxAge)
+		j := sort.Search(len(s.points), func(i int) bool { return !s.points[i].Time.Before(oldest) })
+		s.points = s.points[j:]
+	}
+	return nil
+}
+
+// Range returns the points from (inclusive) up to to (exclusive).
+func (s *TimeSeries) Range(from, to time.Time) []Point {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i := sort.Search(len(s.points), func(i int) bool { return !s.points[i].Time.Before(from) })
+	j := sort.Search(len(s.points), func(i int) bool { return !s.points[i].Time.Before(to) })
+	return append([]Point(nil), s.points[i:j]...)
+}
+
+// Downsample returns the points from (inclusive) up to to (exclusive),
+// averaged per interval of length step. The time of each resulting point is
+// the start of its interval; intervals without points are omitted. A step of
+// zero (or less) returns the points as they are.
+func (s *TimeSeries) Downsample(from, to time.Time, step time.Duration) []Point {
+	if step <= 0 {
+		return s.Range(from, to)
+	}
+	var result []Point
+	var sums map[string]float64
+	var counts map[string]int
+	flush := func() {
+		if len(result) == 0 {
+			return
+		}
+		p := &result[len(result)-1]
+		for code, sum := range sums {
+			p.Values[code] = sum / float64(counts[code])
+		}
+	}
+
+	for _, p := range s.Range(from, to) {
+		start := from.Add(p.Time.Sub(from) / step * step)
+		if len(result) == 0 || !result[len(result)-1].Time.Equal(start) {
+			flush()
+			result = append(result, Point{Time: start, Values: make(map[string]float64)})
+			sums = make(map[string]float64)
+			counts = make(map[string]int)
+		}
+		for code, v := range p.Values {
+			sums[code] += v
+			counts[code]++
+		}
+	}
+	flush()
+	return result
+}
+
+// Append calls Add, to implement HistoryStore.
+func (s *TimeSeries) Append(t Telegram) error {
+	return s.Add(t)
+}
+
+// Query calls Range, to implement HistoryStore. It never fails.
+func (s *TimeSeries) Query(from, to time.Time) ([]Point, error) {
+	return s.Range(from, to), nil
+}
+
+// Aggregate calls Downsample, to implement HistoryStore. It only fails for a
+// step that is not positive.
+func (s *TimeSeries) Aggregate(from, to time.Time, step time.Duration) ([]Point, error) {
+	if step <= 0 {
+		return nil, ErrorInvalidStep
+	}
+	return s.Downsample(from, to, step), nil
+}
@@ -0,0 +1,57 @@
+package dsmr4p1
+
+import (
+	"sort"
+	"time"
+)
+
+// Timestamp is a timestamp found in a telegram, along with its origin.
+type Timestamp struct {
+	Code  string // The OBIS code of the line.
+	Index int    // The index of the bracket in the line.
+	Time  time.Time
+}
+
+// Timestamps returns all timestamps in the telegram, e.g. the time of the
+// telegram itself (0-0:1.0.0), the capture times of M-Bus readings and the
+// times in the power failure log. This makes it easy to check the consistency
+// of the clocks involved. The timestamps are ordered by OBIS code and index.
+func (t Telegram) Timestamps() ([]Timestamp, error) {
+	r, err := t.Parse()
+	if err != nil {
+		return nil, err
+	}
+	codes := make([]string, 0, len(r))
+	for code := range r {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	var timestamps []Timestamp
+	for _, code := range codes {
+		for i, v := range r[code] {
+			if !isTimestamp(v) {
+				continue
+			}
+			ts, err := ParseTimestamp(v)
+			if err != nil {
+				return nil, err
+			}
+			timestamps = append(timestamps, Timestamp{Code: code, Index: i, Time: ts})
+		}
+	}
+	return timestamps, nil
+}
+
+// isTimestamp reports whether s has the YYMMDDhhmmssX format of a timestamp.
+func isTimestamp(s string) bool {
+	if len(s) != 13 || s[12] != 'S' && s[12] != 'W' {
+		return false
+	}
+	for _, c := range s[:12] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,8 @@
+//go:build js && wasm
+// +build js,wasm
+
+package dsmr4p1
+
+// There is no time zone database to load Europe/Amsterdam from when running in
+// a browser, so embed the one from the standard library.
+import _ "time/tzdata"
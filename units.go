@@ -0,0 +1,95 @@
+package dsmr4p1
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrorUnexpectedUnit indicates that a value in a telegram does not carry the
+// unit expected for its OBIS code, which hints at a misconfigured meter or a
+// misparsed line.
+var ErrorUnexpectedUnit = errors.New("value does not have the expected unit")
+
+// ErrorOutOfRange indicates that a value in a telegram lies outside the
+// plausible range for its OBIS code, which hints at e.g. a bit error.
+var ErrorOutOfRange = errors.New("value outside plausible range")
+
+// ExpectedUnits maps OBIS codes to the unit their values should carry, as
+// returned by ParseValueWithUnit (so without the "k" prefix). The value of a
+// code is taken from its last bracket, which covers e.g. the gas reading that
+// is preceded by the time of capture.
+var ExpectedUnits = map[string]string{
+	ObisElectricityDelivered: "Wh", ObisElectricityDeliveredTariff1: "Wh", ObisElectricityDeliveredTariff2: "Wh",
+	ObisElectricityReceived: "Wh", ObisElectricityReceivedTariff1: "Wh", ObisElectricityReceivedTariff2: "Wh",
+	ObisPowerDelivered: "W", ObisPowerReceived: "W",
+	ObisPowerDeliveredL1: "W", ObisPowerDeliveredL2: "W", ObisPowerDeliveredL3: "W",
+	ObisPowerReceivedL1: "W", ObisPowerReceivedL2: "W", ObisPowerReceivedL3: "W",
+	ObisVoltageL1: "V", ObisVoltageL2: "V", ObisVoltageL3: "V",
+	ObisCurrentL1: "A", ObisCurrentL2: "A", ObisCurrentL3: "A",
+	ObisPowerThreshold: "W",
+	ObisGasDelivered:   "m3",
+}
+
+// CheckUnits checks that every value in telegram t whose OBIS code is listed in
+// ExpectedUnits carries the expected unit. The returned error wraps
+// ErrorUnexpectedUnit and names the first offending code (in sorted order).
+func CheckUnits(t Telegram) error {
+	r, err := t.Parse()
+	if err != nil {
+		return err
+	}
+	codes := make([]string, 0, len(r))
+	for code := range r {
+		if _, ok := ExpectedUnits[code]; ok {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		// A value without any unit yields an empty unit.
+		_, unit, _ := numericValue(r[code])
+		if want := ExpectedUnits[code]; unit != want {
+			return fmt.Errorf("%w: %s has %q instead of %q", ErrorUnexpectedUnit, code, unit, want)
+		}
+	}
+	return nil
+}
+
+// PlausibleRanges maps OBIS codes to the range of plausible values, in base
+// units, for residential connections. Only Min and Max of the ranges are used.
+// Adjust it to your installation before calling CheckRanges.
+var PlausibleRanges = map[string]ValueRange{
+	ObisVoltageL1: {Min: 180, Max: 260}, ObisVoltageL2: {Min: 180, Max: 260}, ObisVoltageL3: {Min: 180, Max: 260},
+	ObisCurrentL1: {Min: 0, Max: 100}, ObisCurrentL2: {Min: 0, Max: 100}, ObisCurrentL3: {Min: 0, Max: 100},
+	ObisPowerDelivered: {Min: 0, Max: 70000}, ObisPowerReceived: {Min: 0, Max: 70000},
+	ObisPowerDeliveredL1: {Min: 0, Max: 25000}, ObisPowerDeliveredL2: {Min: 0, Max: 25000}, ObisPowerDeliveredL3: {Min: 0, Max: 25000},
+	ObisPowerReceivedL1: {Min: 0, Max: 25000}, ObisPowerReceivedL2: {Min: 0, Max: 25000}, ObisPowerReceivedL3: {Min: 0, Max: 25000},
+}
+
+// CheckRanges checks that every value in telegram t whose OBIS code is listed
+// in PlausibleRanges lies within its range. The returned error wraps
+// ErrorOutOfRange and names the first offending code (in sorted order).
+func CheckRanges(t Telegram) error {
+	r, err := t.Parse()
+	if err != nil {
+		return err
+	}
+	codes := make([]string, 0, len(r))
+	for code := range r {
+		if _, ok := PlausibleRanges[code]; ok {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		v, _, ok := numericValue(r[code])
+		if !ok {
+			continue
+		}
+		if vr := PlausibleRanges[code]; v < vr.Min || v > vr.Max {
+			return fmt.Errorf("%w: %s is %g, expected [%g, %g]", ErrorOutOfRange, code, v, vr.Min, vr.Max)
+		}
+	}
+	return nil
+}
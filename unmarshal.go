@@ -0,0 +1,123 @@
+package dsmr4p1
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrorUnmarshalTarget indicates that Unmarshal was passed something other
+// than a non-nil pointer to a struct.
+var ErrorUnmarshalTarget = errors.New("unmarshal needs a non-nil pointer to a struct")
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	measurementType = reflect.TypeOf(Measurement{})
+	stringsType     = reflect.TypeOf([]string(nil))
+)
+
+// Unmarshal stores the values of telegram t in the fields of the struct v
+// points to, as selected by their dsmr tags, so applications can define
+// exactly the subset of the telegram they care about:
+//
+//	var v struct {
+//		Time  time.Time           `dsmr:"0-0:1.0.0"`
+//		Power dsmr4p1.Measurement `dsmr:"1-0:1.7.0"`
+//		Gas   float64             `dsmr:"0-1:24.2.1"`
+//	}
+//	err := dsmr4p1.Unmarshal(t, &v)
+//
+// Fields of type time.Time get the timestamp in the first bracket, which for
+// e.g. the gas reading is its time of capture. Fields of type float64 (in base
+// units, see ParseValueWithUnit), int, uint, string and Measurement get the
+// value in the last bracket, and fields of type []string get all values. An
+// integer that doesn't fit its field is an error. Fields of codes missing from
+// the telegram are left as is.
+func Unmarshal(t Telegram, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return ErrorUnmarshalTarget
+	}
+	r, err := t.Parse()
+	if err != nil {
+		return err
+	}
+
+	rv = rv.Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		code, ok := f.Tag.Lookup("dsmr")
+		if !ok || f.PkgPath != "" {
+			continue
+		}
+		values := r[code]
+		if len(values) == 0 {
+			continue
+		}
+		if err := setField(rv.Field(i), values); err != nil {
+			return fmt.Errorf("%s (field %s): %w", code, f.Name, err)
+		}
+	}
+	return nil
+}
+
+// setField sets field to the values of an OBIS code.
+func setField(field reflect.Value, values []string) error {
+	last := values[len(values)-1]
+	switch field.Type() {
+	case timeType:
+		ts, err := ParseTimestamp(values[0])
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(ts))
+		return nil
+	case measurementType:
+		m, err := ParseMeasurement(last)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(m))
+		return nil
+	case stringsType:
+		field.Set(reflect.ValueOf(append([]string(nil), values...)))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		var err error
+		if strings.Contains(last, "*") {
+			f, _, err = ParseValueWithUnit(last)
+		} else {
+			f, err = strconv.ParseFloat(last, 64)
+		}
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		// Parsing with the size of the field rejects values that don't fit.
+		n, err := strconv.ParseInt(last, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(last, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.String:
+		field.SetString(last)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}